@@ -18,41 +18,96 @@ package client
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httputil"
+	"net/url"
 	"os"
 	"strings"
 
 	"github.com/fnproject/fn_go/provider"
+	"github.com/fnproject/fn_go/provider/defaultprovider"
 	"github.com/go-openapi/runtime/logger"
+	"github.com/mattn/go-isatty"
 )
 
 const (
 	MaximumRequestBodySize = 10 * 1024 * 1024 // bytes
+
+	// uploadProgressSize is the body size above which the progress of an upload is shown on a terminal
+	uploadProgressSize = 1024 * 1024 // bytes
 )
 
+// BodyTooLargeError is returned by Invoke when the request body is larger than its maximum size
+type BodyTooLargeError struct {
+	MaxBodySize int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("the request body is larger than the maximum of %s, set --max-body to send a larger one",
+		formatBytes(e.MaxBodySize))
+}
+
+// EnvAsHeader sets selectedEnv, or all of the environment if it is empty, as headers of req
+//
+// Deprecated: use EnvHeaders
 func EnvAsHeader(req *http.Request, selectedEnv []string) {
-	detectedEnv := os.Environ()
-	if len(selectedEnv) > 0 {
-		detectedEnv = selectedEnv
+	env := selectedEnv
+	if len(env) == 0 {
+		env = os.Environ()
+	}
+	for name, values := range EnvHeaders(env) {
+		req.Header[name] = values
 	}
+}
 
-	for _, e := range detectedEnv {
-		kv := strings.Split(e, "=")
+// EnvHeaders returns each of env, NAME=value or just NAME to take the value from the environment, as a header
+// called NAME. Names that aren't set in the environment are left out.
+func EnvHeaders(env []string) http.Header {
+	header := http.Header{}
+	for _, e := range env {
+		kv := strings.SplitN(e, "=", 2)
 		name := kv[0]
-		req.Header.Set(name, os.Getenv(name))
+		if name == "" {
+			continue
+		}
+		if len(kv) == 2 {
+			header.Add(name, kv[1])
+		} else if v, ok := os.LookupEnv(name); ok {
+			header.Add(name, v)
+		}
 	}
+	return header
 }
 
 // InvokeRequest are the parameters provided to Invoke
 type InvokeRequest struct {
-	URL         string
-	Content     io.Reader
-	Env         []string
+	URL string
+	// Method is the HTTP method of the call, POST if empty
+	Method string
+	// Header is sent with the call, along with the headers of Env
+	Header http.Header
+	// Query is added to the query of URL
+	Query   url.Values
+	Content io.Reader
+	// Env is sent as headers, see EnvHeaders
+	Env []string
+	// ContentType is the Content-Type of Content, the Content-Type of Header or text/plain if empty
 	ContentType string
-	// TODO headers should be their real type?
+	// MaxBodySize is the largest Content that is sent, MaximumRequestBodySize if 0
+	MaxBodySize int64
+	// DisableCompression leaves the response as the function sent it. Otherwise a gzipped response is asked
+	// for and transparently decompressed, without its Content-Encoding and Content-Length.
+	DisableCompression bool
+}
+
+// streamsBody reports whether the request body can be streamed with p. Providers whose auth may sign the
+// body, and so need all of it before it is sent, read it first: any but those known not to sign requests.
+func streamsBody(p provider.Provider) bool {
+	_, ok := p.(*defaultprovider.Provider)
+	return ok
 }
 
 // Invoke calls the fn invoke API
@@ -62,36 +117,97 @@ func Invoke(provider provider.Provider, ireq InvokeRequest) (*http.Response, err
 	env := ireq.Env
 	contentType := ireq.ContentType
 	method := "POST"
+	if ireq.Method != "" {
+		method = strings.ToUpper(ireq.Method)
+	}
+	if len(ireq.Query) > 0 {
+		u, err := url.Parse(invokeURL)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing invoke URL %s: %s", invokeURL, err)
+		}
+		q := u.Query()
+		for k, vs := range ireq.Query {
+			for _, v := range vs {
+				q.Add(k, v)
+			}
+		}
+		u.RawQuery = q.Encode()
+		invokeURL = u.String()
+	}
+	maxBodySize := ireq.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = MaximumRequestBodySize
+	}
 
-	// Read the request body (up to the maximum size), as this is used in the
-	// authentication signature (Content-Length & Date must be set correctly)
 	var buffer bytes.Buffer
-	if content != nil {
-		_, err := io.Copy(&buffer, io.LimitReader(content, MaximumRequestBodySize))
+	var body io.Reader = &buffer
+	bodySize := int64(0)
+	streamed := false
+	if content != nil && !streamsBody(provider) {
+		// Read the request body, as this is used in the authentication
+		// signature (Content-Length & Date must be set correctly)
+		n, err := io.Copy(&buffer, io.LimitReader(content, maxBodySize+1))
 		if err != nil {
 			return nil, fmt.Errorf("Error creating request body: %s", err)
 		}
+		if n > maxBodySize {
+			return nil, &BodyTooLargeError{MaxBodySize: maxBodySize}
+		}
+		bodySize = n
+		body = bytes.NewReader(buffer.Bytes())
+	} else if content != nil {
+		// otherwise the body is streamed, with a length if it is known up front
+		bodySize = contentSize(content)
+		if bodySize > maxBodySize {
+			return nil, &BodyTooLargeError{MaxBodySize: maxBodySize}
+		}
+		body = &limitedBody{reader: content, max: maxBodySize}
+		streamed = true
 	}
-	req, err := http.NewRequest(method, invokeURL, &buffer)
+	// small bodies of unknown size, eg: piped in, are only shown once they turn out to be large
+	if content != nil && (bodySize > uploadProgressSize || bodySize < 0) && isatty.IsTerminal(os.Stderr.Fd()) {
+		body = newUploadProgress(os.Stderr, body, bodySize)
+	}
+
+	req, err := http.NewRequest(method, invokeURL, body)
 	if err != nil {
 		return nil, fmt.Errorf("Error creating request to service: %s", err)
 	}
+	if bodySize >= 0 {
+		req.ContentLength = bodySize
+	}
+	if bodySize == 0 {
+		req.Body = http.NoBody
+	}
 
+	for name, values := range ireq.Header {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	for name, values := range EnvHeaders(env) {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
-	} else {
+	} else if req.Header.Get("Content-Type") == "" {
 		req.Header.Set("Content-Type", "text/plain")
 	}
 
-	if len(env) > 0 {
-		EnvAsHeader(req, env)
+	var base http.RoundTripper = http.DefaultTransport
+	if ireq.DisableCompression {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.DisableCompression = true
+		base = t
 	}
-
-	transport := provider.WrapCallTransport(http.DefaultTransport)
+	transport := provider.WrapCallTransport(base)
 	httpClient := http.Client{Transport: transport}
 
 	if logger.DebugEnabled() {
-		b, err := httputil.DumpRequestOut(req, content != nil)
+		// dumping a streamed body would read all of it into memory
+		b, err := httputil.DumpRequestOut(req, content != nil && !streamed)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "error dumping req", err)
 		}
@@ -101,6 +217,10 @@ func Invoke(provider provider.Provider, ireq InvokeRequest) (*http.Response, err
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
+		var tooLarge *BodyTooLargeError
+		if errors.As(err, &tooLarge) {
+			return nil, tooLarge
+		}
 		return nil, fmt.Errorf("Error invoking function: %s", err)
 	}
 
@@ -0,0 +1,231 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn_go/provider"
+	"github.com/fnproject/fn_go/provider/defaultprovider"
+	"github.com/fnproject/fn_go/provider/oracle"
+)
+
+func TestInvokeMaxBodySize(t *testing.T) {
+	var received string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		received = string(b)
+	}))
+	defer srv.Close()
+
+	p := &defaultprovider.Provider{}
+	resp, err := Invoke(p, InvokeRequest{URL: srv.URL, Content: strings.NewReader("12345"), MaxBodySize: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if received != "12345" {
+		t.Fatalf("expected the whole body to be streamed, got %q", received)
+	}
+
+	// bodies over the maximum fail rather than being cut off, streamed or read to be signed
+	_, err = Invoke(p, InvokeRequest{URL: srv.URL, Content: strings.NewReader("123456"), MaxBodySize: 5})
+	if _, ok := err.(*BodyTooLargeError); !ok {
+		t.Fatalf("expected a BodyTooLargeError streaming the body, got %v", err)
+	}
+	_, err = Invoke(&oracle.OracleProvider{}, InvokeRequest{URL: srv.URL, Content: strings.NewReader("123456"), MaxBodySize: 5})
+	if _, ok := err.(*BodyTooLargeError); !ok {
+		t.Fatalf("expected a BodyTooLargeError reading the body, got %v", err)
+	}
+}
+
+// unknownProvider is a provider Invoke doesn't know, which may sign request bodies
+type unknownProvider struct {
+	defaultprovider.Provider
+}
+
+func TestInvokeBodyLength(t *testing.T) {
+	var contentLength int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentLength = r.ContentLength
+	}))
+	defer srv.Close()
+
+	// a body of unknown size is streamed only with providers known not to sign it
+	for _, tc := range []struct {
+		p        provider.Provider
+		expected int64
+	}{
+		{&defaultprovider.Provider{}, -1},
+		{&unknownProvider{}, 6},
+	} {
+		resp, err := Invoke(tc.p, InvokeRequest{URL: srv.URL, Content: io.MultiReader(strings.NewReader("123456"))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if contentLength != tc.expected {
+			t.Errorf("expected a Content-Length of %d with %T, got %d", tc.expected, tc.p, contentLength)
+		}
+	}
+}
+
+func TestUploadProgress(t *testing.T) {
+	for _, tc := range []struct {
+		size  int
+		shown bool
+	}{
+		{size: 3},
+		{size: uploadProgressSize + 1, shown: true},
+	} {
+		var out bytes.Buffer
+		ioutil.ReadAll(newUploadProgress(&out, strings.NewReader(strings.Repeat("a", tc.size)), -1))
+		if shown := strings.Contains(out.String(), "Uploading"); shown != tc.shown {
+			t.Errorf("expected progress of a %d byte body to be shown: %v, got %q", tc.size, tc.shown, out.String())
+		}
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	for s, expected := range map[string]int64{"512": 512, "64KB": 64 << 10, "20mb": 20 << 20, "1G": 1 << 30} {
+		if n, err := ParseBytes(s); err != nil || n != expected {
+			t.Errorf("expected %s to be %d bytes, got %d, %v", s, expected, n, err)
+		}
+	}
+	for _, s := range []string{"", "MB", "10TB", "-1"} {
+		if _, err := ParseBytes(s); err == nil {
+			t.Errorf("expected %q to be invalid", s)
+		}
+	}
+	if s := formatBytes(10 << 20); s != "10MB" {
+		t.Errorf("expected 10MB, got %s", s)
+	}
+}
+
+func TestInvokeMethodHeadersAndQuery(t *testing.T) {
+	var req *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+	}))
+	defer srv.Close()
+
+	os.Setenv("FN_TEST_TOKEN", "secret")
+	defer os.Unsetenv("FN_TEST_TOKEN")
+
+	resp, err := Invoke(&defaultprovider.Provider{}, InvokeRequest{
+		URL:    srv.URL + "/invoke?a=1",
+		Method: "put",
+		Header: http.Header{"Accept": {"application/json"}, "Content-Type": {"application/json"}},
+		Query:  url.Values{"b": {"2", "3"}},
+		Env:    []string{"FN_TEST_TOKEN", "X-Region=eu"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if req.Method != http.MethodPut {
+		t.Errorf("expected a PUT, got %s", req.Method)
+	}
+	if q := req.URL.Query(); q.Get("a") != "1" || strings.Join(q["b"], ",") != "2,3" {
+		t.Errorf("expected the URL and given query parameters, got %s", req.URL.RawQuery)
+	}
+	for name, expected := range map[string]string{
+		"Accept":        "application/json",
+		"Content-Type":  "application/json",
+		"Fn_test_token": "secret",
+		"X-Region":      "eu",
+	} {
+		if v := req.Header.Get(name); v != expected {
+			t.Errorf("expected header %s to be %q, got %q", name, expected, v)
+		}
+	}
+}
+
+func TestEnvHeaders(t *testing.T) {
+	os.Setenv("FN_TEST_SET", "a=b")
+	defer os.Unsetenv("FN_TEST_SET")
+	os.Unsetenv("FN_TEST_UNSET")
+
+	header := EnvHeaders([]string{"FN_TEST_SET", "FN_TEST_UNSET", "X-Empty=", "=ignored"})
+	if v := header.Get("FN_TEST_SET"); v != "a=b" {
+		t.Errorf("expected the value of FN_TEST_SET, got %q", v)
+	}
+	if _, ok := header["Fn_test_unset"]; ok {
+		t.Errorf("expected no header for a variable that isn't set, got %v", header)
+	}
+	if v, ok := header["X-Empty"]; !ok || v[0] != "" {
+		t.Errorf("expected an empty X-Empty header, got %v", header)
+	}
+	if len(header) != 2 {
+		t.Errorf("expected 2 headers, got %v", header)
+	}
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set("FN_TEST_SET", "old")
+	EnvAsHeader(req, []string{"FN_TEST_SET"})
+	if v := req.Header["Fn_test_set"]; len(v) != 1 || v[0] != "a=b" {
+		t.Errorf("expected EnvAsHeader to set FN_TEST_SET, got %v", req.Header)
+	}
+}
+
+func TestInvokeDisableCompression(t *testing.T) {
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	zw.Write([]byte("hello"))
+	zw.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") == "gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipped.Bytes())
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name     string
+		ireq     InvokeRequest
+		encoding string
+		body     string
+	}{
+		{name: "default", ireq: InvokeRequest{URL: srv.URL}, body: "hello"},
+		{name: "disabled", ireq: InvokeRequest{URL: srv.URL, DisableCompression: true}, body: "hello"},
+		{name: "disabled, asked for gzip", ireq: InvokeRequest{URL: srv.URL, DisableCompression: true, Header: http.Header{"Accept-Encoding": {"gzip"}}},
+			encoding: "gzip", body: gzipped.String()},
+	} {
+		resp, err := Invoke(&defaultprovider.Provider{}, tc.ireq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if encoding := resp.Header.Get("Content-Encoding"); encoding != tc.encoding || string(b) != tc.body {
+			t.Errorf("%s: expected body %q with Content-Encoding %q, got %q with %q", tc.name, tc.body, tc.encoding, b, encoding)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// contentSize returns the size of what is left to read of content when it is a regular file, eg: stdin
+// redirected from a file, or -1 when it isn't known up front
+func contentSize(content io.Reader) int64 {
+	f, ok := content.(*os.File)
+	if !ok {
+		return -1
+	}
+	stat, err := f.Stat()
+	if err != nil || !stat.Mode().IsRegular() {
+		return -1
+	}
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1
+	}
+	return stat.Size() - offset
+}
+
+// limitedBody is a streamed request body that fails with a BodyTooLargeError once more than max bytes are read
+type limitedBody struct {
+	reader io.Reader
+	max    int64
+	read   int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	n, err := l.reader.Read(p)
+	l.read += int64(n)
+	if l.read > l.max {
+		return n, &BodyTooLargeError{MaxBodySize: l.max}
+	}
+	return n, err
+}
+
+// uploadProgress reports how much of a request body of size bytes, -1 if unknown, has been sent on a line of
+// out, once more than uploadProgressSize has been sent so that small bodies are sent quietly
+type uploadProgress struct {
+	reader  io.Reader
+	out     io.Writer
+	size    int64
+	sent    int64
+	printed time.Time
+	shown   bool
+	done    bool
+}
+
+func newUploadProgress(out io.Writer, reader io.Reader, size int64) *uploadProgress {
+	return &uploadProgress{reader: reader, out: out, size: size}
+}
+
+func (u *uploadProgress) Read(p []byte) (int, error) {
+	n, err := u.reader.Read(p)
+	u.sent += int64(n)
+	if err == io.EOF && u.shown && !u.done {
+		u.done = true
+		u.print()
+		fmt.Fprintln(u.out)
+	} else if u.sent > uploadProgressSize && time.Since(u.printed) > 200*time.Millisecond {
+		u.print()
+	}
+	return n, err
+}
+
+func (u *uploadProgress) print() {
+	u.printed = time.Now()
+	u.shown = true
+	if u.size > 0 {
+		fmt.Fprintf(u.out, "\r\033[KUploading %s of %s (%d%%)", formatBytes(u.sent), formatBytes(u.size), u.sent*100/u.size)
+	} else {
+		fmt.Fprintf(u.out, "\r\033[KUploading %s", formatBytes(u.sent))
+	}
+}
+
+// formatBytes formats n bytes in the largest unit, eg: 10MB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 3; m /= unit {
+		div *= unit
+		exp++
+	}
+	value := float64(n) / float64(div)
+	if value == float64(int64(value)) {
+		return fmt.Sprintf("%d%cB", int64(value), "KMGT"[exp])
+	}
+	return fmt.Sprintf("%.1f%cB", value, "KMGT"[exp])
+}
+
+// ParseBytes parses a size in bytes with an optional unit, eg: 512, 64KB or 20MB, where a KB is 1024 bytes
+func ParseBytes(s string) (int64, error) {
+	digits := strings.TrimRightFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	multipliers := map[string]int64{"": 1, "B": 1, "K": 1 << 10, "KB": 1 << 10, "M": 1 << 20, "MB": 1 << 20, "G": 1 << 30, "GB": 1 << 30}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	m, ok := multipliers[strings.ToUpper(strings.TrimSpace(s[len(digits):]))]
+	if err != nil || !ok || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q, must be a number of bytes with an optional unit, eg: 20MB", s)
+	}
+	return n * m, nil
+}
@@ -17,6 +17,7 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -42,7 +43,12 @@ func BuildCommand() cli.Command {
 }
 
 type buildcmd struct {
-	noCache bool
+	noCache      bool
+	platform     string
+	export       string
+	exportFormat string
+	sbom         bool
+	sbomFormat   string
 }
 
 func (b *buildcmd) flags() []cli.Flag {
@@ -61,10 +67,44 @@ func (b *buildcmd) flags() []cli.Flag {
 			Name:  "build-arg",
 			Usage: "Set build-time variables",
 		},
+		cli.StringSliceFlag{
+			Name:  "build-arg-file",
+			Usage: "Read build-time variables from a file of KEY=VALUE lines, --build-arg takes precedence",
+		},
+		cli.StringSliceFlag{
+			Name:  "secret",
+			Usage: "Pass a BuildKit secret to the build, which unlike a build arg isn't kept in the image, eg: id=npmrc,src=$HOME/.npmrc. Java and Kotlin builds use a maven-settings secret as Maven settings, Node builds an npmrc secret as .npmrc",
+		},
 		cli.StringFlag{
 			Name:  "working-dir, w",
 			Usage: "Specify the working directory to build a function, must be the full path.",
 		},
+		cli.StringFlag{
+			Name:        "platform",
+			Usage:       "Comma separated platforms to build the image for with docker buildx, eg: linux/amd64,linux/arm64. Overrides platforms in func.yaml",
+			Destination: &b.platform,
+		},
+		cli.StringFlag{
+			Name:        "export",
+			Usage:       "Write the built image to an archive at this path, with its name, version and image in a .json file next to it, to push or deploy with --from-archive",
+			Destination: &b.export,
+		},
+		cli.StringFlag{
+			Name:        "export-format",
+			Usage:       "Format of the --export archive, docker or oci",
+			Value:       common.ArchiveFormatDocker,
+			Destination: &b.exportFormat,
+		},
+		cli.BoolFlag{
+			Name:        "sbom",
+			Usage:       "Write a software bill of materials of the function's dependencies to .fn, and next to the --export archive. Set sbom in func.yaml to write one on every build",
+			Destination: &b.sbom,
+		},
+		cli.StringFlag{
+			Name:        "sbom-format",
+			Usage:       "Format of the software bill of materials, spdx or cyclonedx. Overrides sbom in func.yaml, and defaults to spdx",
+			Destination: &b.sbomFormat,
+		},
 	}
 }
 
@@ -72,13 +112,32 @@ func (b *buildcmd) flags() []cli.Flag {
 func (b *buildcmd) build(c *cli.Context) error {
 	dir := common.GetDir(c)
 
+	export := b.export
+	if export != "" {
+		// relative to where fn was run, not the function directory
+		abs, err := filepath.Abs(export)
+		if err != nil {
+			return err
+		}
+		export = abs
+	}
+	// like --export, build arg files and secrets are relative to where fn was run
+	buildArgs, err := common.BuildArgs(c)
+	if err != nil {
+		return err
+	}
+	secrets, err := common.BuildSecrets(c)
+	if err != nil {
+		return err
+	}
+
 	path := c.Args().First()
 	if path != "" {
 		fmt.Printf("Building function at: ./%s\n", path)
 		dir = filepath.Join(dir, path)
 	}
 
-	err := os.Chdir(dir)
+	err = os.Chdir(dir)
 	if err != nil {
 		return err
 	}
@@ -96,22 +155,51 @@ func (b *buildcmd) build(c *cli.Context) error {
 			return err
 		}
 
-		buildArgs := c.StringSlice("build-arg")
-		ff, err = common.BuildFuncV20180708(common.IsVerbose(), fpath, ff, buildArgs, b.noCache)
+		if b.platform != "" {
+			ff.Platforms = common.ParsePlatforms(b.platform)
+		}
+		if b.sbomFormat != "" {
+			ff.SBOM = b.sbomFormat
+		} else if b.sbom && ff.SBOM == "" {
+			ff.SBOM = common.SBOMFormatSPDX
+		}
+		if export != "" && len(ff.Platforms) > 1 {
+			return errors.New("--export cannot be used with more than one platform, images for several platforms are only kept by pushing them")
+		}
+		ff, err = common.BuildFuncV20180708(common.StdBuildOutput(), common.IsVerbose(), fpath, ff, buildArgs, secrets, b.noCache, false)
 		if err != nil {
 			return err
 		}
 
 		fmt.Printf("Function %v built successfully.\n", ff.ImageNameV20180708())
+
+		if export != "" {
+			if err := common.ExportImage(ff, export, b.exportFormat); err != nil {
+				return err
+			}
+			fmt.Printf("Function %v exported to %v.\n", ff.ImageNameV20180708(), export)
+			if ff.SBOM != "" {
+				sbomPath := common.ArchiveSBOMPath(export, ff.SBOM)
+				if err := common.WriteSBOMV20180708(filepath.Dir(fpath), ff, ff.SBOM, sbomPath); err != nil {
+					return err
+				}
+				fmt.Printf("Software bill of materials written to %v\n", sbomPath)
+			}
+		}
 		return nil
 
 	default:
+		if export != "" {
+			return errors.New("--export requires a func file with schema_version 20180708 or later")
+		}
+		if b.sbom || b.sbomFormat != "" {
+			return errors.New("--sbom requires a func file with schema_version 20180708 or later")
+		}
 		fpath, ff, err := common.FindAndParseFuncfile(dir)
 		if err != nil {
 			return err
 		}
 
-		buildArgs := c.StringSlice("build-arg")
 		ff, err = common.BuildFunc(common.IsVerbose(), fpath, ff, buildArgs, b.noCache)
 		if err != nil {
 			return err
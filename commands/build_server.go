@@ -113,7 +113,7 @@ func (b *BuildServerCmd) buildServer(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	err = common.RunBuild(common.IsVerbose(), dir, c.String("tag"), "Dockerfile", nil, b.noCache)
+	err = common.RunBuild(common.StdBuildOutput(), common.IsVerbose(), dir, c.String("tag"), "Dockerfile", nil, common.BuildOptions{NoCache: b.noCache})
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/cli/config"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+	"github.com/urfave/cli"
+)
+
+func TestBuildPathsRelativeToWorkingDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fakeDocker(t, dir, "")()
+
+	// build logs are written to the fn config directory in the home directory
+	home := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	homedir.DisableCache = true
+	defer func() {
+		os.Setenv("HOME", home)
+		homedir.DisableCache = false
+	}()
+	registry := viper.GetString(config.EnvFnRegistry)
+	viper.Set(config.EnvFnRegistry, "owner")
+	defer viper.Set(config.EnvFnRegistry, registry)
+
+	writeTestFiles(t, dir, map[string]string{
+		"args.env":         "GREETING=hello\n",
+		"npmrc":            "",
+		"hello/func.yaml":  "schema_version: 20180708\nname: hello\nversion: 0.0.1\nruntime: docker\n",
+		"hello/Dockerfile": "FROM scratch\n",
+		"hello/args.env":   "GREETING=wrong\n",
+	})
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	app := cli.NewApp()
+	app.Commands = []cli.Command{BuildCommand()}
+	if err := app.Run([]string{"fn", "build", "--build-arg-file", "args.env", "--secret", "id=npmrc,src=npmrc", "hello"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var build string
+	for _, call := range fakeDockerCalls(t, dir) {
+		if strings.HasPrefix(call, "build ") {
+			build = call
+		}
+	}
+	for _, expected := range []string{"--build-arg GREETING=hello ", "--secret id=npmrc,src=" + filepath.Join(dir, "npmrc") + " "} {
+		if !strings.Contains(build, expected) {
+			t.Errorf("expected the build to have %q, relative to where fn was run, got %q", expected, build)
+		}
+	}
+}
@@ -41,12 +41,14 @@ var Commands = Cmd{
 	"create":       CreateCommand(),
 	"delete":       DeleteCommand(),
 	"deploy":       DeployCommand(),
+	"generate":     GenerateCommand(),
 	"get":          GetCommand(),
 	"init":         InitCommand(),
 	"inspect":      InspectCommand(),
 	"list":         ListCommand(),
 	"migrate":      MigrateCommand(),
 	"push":         PushCommand(),
+	"rollback":     RollbackCommand(),
 	"start":        StartCommand(),
 	"stop":         StopCommand(),
 	"unset":        UnsetCommand(),
@@ -94,6 +96,10 @@ var DeleteCmds = Cmd{
 	"config":    ConfigCommand("delete"),
 }
 
+var GenerateCmds = Cmd{
+	"dockerfile": GenerateDockerfileCommand(),
+}
+
 var GetCmds = Cmd{
 	"config": ConfigCommand("get"),
 }
@@ -113,6 +119,10 @@ var ListCmds = Cmd{
 	"contexts":  context.List(),
 }
 
+var RollbackCmds = Cmd{
+	"functions": fn.Rollback(),
+}
+
 var UnsetCmds = Cmd{
 	"config":  ConfigCommand("unset"),
 	"context": context.Unset(),
@@ -24,15 +24,19 @@ import (
 	"errors"
 	"fmt"
 	"github.com/fnproject/fn_go/provider/oracle"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/fatih/color"
 	client "github.com/fnproject/cli/client"
 	common "github.com/fnproject/cli/common"
+	"github.com/fnproject/cli/config"
 	apps "github.com/fnproject/cli/objects/app"
 	function "github.com/fnproject/cli/objects/fn"
 	trigger "github.com/fnproject/cli/objects/trigger"
@@ -41,6 +45,7 @@ import (
 	"github.com/oracle/oci-go-sdk/v48/artifacts"
 	ociCommon "github.com/oracle/oci-go-sdk/v48/common"
 	"github.com/oracle/oci-go-sdk/v48/keymanagement"
+	"github.com/spf13/viper"
 	"github.com/urfave/cli"
 )
 
@@ -118,6 +123,37 @@ type deploycmd struct {
 	registry  string
 	all       bool
 	noBump    bool
+	parallel  int
+	force     bool
+	dryRun    bool
+	output    string
+	prune     bool
+	yes       bool
+	sync      bool
+	pinDigest bool
+	since     string
+	platform  string
+
+	// buildArgs are those of --build-arg-file and --build-arg
+	buildArgs []string
+	// secrets are those of --secret
+	secrets []string
+
+	// archive is the image archive of --from-archive, deployed instead of building the function
+	fromArchive string
+	archive     *common.ImageArchive
+
+	// sharedPaths are from app.yaml, sinceChanged is the func files with changes since the --since ref
+	sharedPaths  []string
+	sinceChanged map[string]bool
+
+	state *common.DeployState
+	// stdout gets the report of --output and the plan of --dry-run, out the progress of the deploy, which
+	// goes to stderr with --output json so that stdout only holds the report
+	stdout io.Writer
+	out    io.Writer
+	// stdin is where confirmations are read from
+	stdin io.Reader
 }
 
 func (p *deploycmd) flags() []cli.Flag {
@@ -157,15 +193,79 @@ func (p *deploycmd) flags() []cli.Flag {
 			Usage:       "If in root directory containing `app.yaml`, this will deploy all functions",
 			Destination: &p.all,
 		},
+		cli.BoolFlag{
+			Name:        "pin-digest",
+			Usage:       "Update functions with the digest of the pushed image instead of its tag, so that moving the tag does not change what runs. Defaults to the pin-digest setting of the current context",
+			Destination: &p.pinDigest,
+		},
+		cli.StringFlag{
+			Name:        "platform",
+			Usage:       "Comma separated platforms to build images for with docker buildx, eg: linux/amd64,linux/arm64. Overrides platforms in func files",
+			Destination: &p.platform,
+		},
+		cli.StringFlag{
+			Name:        "from-archive",
+			Usage:       "Deploy the image in an archive written by fn build --export, with its version, instead of bumping and building the function",
+			Destination: &p.fromArchive,
+		},
 		cli.BoolFlag{
 			Name:        "no-bump",
 			Usage:       "Do not bump the version, assuming external version management",
 			Destination: &p.noBump,
 		},
+		cli.BoolFlag{
+			Name:        "force, f",
+			Usage:       "Deploy all functions with --all, including those unchanged since their last deploy, and with --prune delete without asking for confirmation",
+			Destination: &p.force,
+		},
+		cli.StringFlag{
+			Name:        "since",
+			Usage:       "With --all, only deploy functions with changes between this git ref and the working tree, or all of them if a shared path in app.yaml changed",
+			Destination: &p.since,
+		},
+		cli.BoolFlag{
+			Name:        "prune",
+			Usage:       "With --all, delete functions in the app that are not in the app tree and triggers no longer declared in func files",
+			Destination: &p.prune,
+		},
+		cli.BoolFlag{
+			Name:        "yes",
+			Usage:       "With --prune, delete without asking for confirmation, but unlike --force still skip unchanged functions",
+			Destination: &p.yes,
+		},
+		cli.BoolFlag{
+			Name:        "sync",
+			Usage:       "Treat app.yaml and func files as the source of truth, removing config and annotations from the server that are not in them",
+			Destination: &p.sync,
+		},
+		cli.BoolFlag{
+			Name:        "dry-run",
+			Usage:       "Print the apps, functions and triggers that would be created or updated, without building or changing anything",
+			Destination: &p.dryRun,
+		},
+		cli.StringFlag{
+			Name:        "output",
+			Usage:       "Output format (json). Prints a report of each deployed function, with durations in seconds, or with --dry-run the deploy plan. Progress is printed to stderr",
+			Destination: &p.output,
+		},
+		cli.IntFlag{
+			Name:        "parallel",
+			Usage:       "Number of functions to build, push and update concurrently when used with --all",
+			Value:       1,
+			Destination: &p.parallel,
+		},
 		cli.StringSliceFlag{
 			Name:  "build-arg",
 			Usage: "Set build time variables",
 		},
+		cli.StringSliceFlag{
+			Name:  "build-arg-file",
+			Usage: "Read build time variables from a file of KEY=VALUE lines, --build-arg takes precedence",
+		},
+		cli.StringSliceFlag{
+			Name:  "secret",
+			Usage: "Pass a BuildKit secret to builds, which unlike a build arg isn't kept in the image, eg: id=npmrc,src=$HOME/.npmrc. Java and Kotlin builds use a maven-settings secret as Maven settings, Node builds an npmrc secret as .npmrc",
+		},
 		cli.StringFlag{
 			Name:  "working-dir,w",
 			Usage: "Specify the working directory to deploy a function, must be the full path.",
@@ -198,6 +298,7 @@ func (p *deploycmd) deploy(c *cli.Context) error {
 		}
 	} else {
 		appName = appf.Name
+		p.sharedPaths = appf.SharedPaths
 	}
 	if p.appName != "" {
 		// flag overrides all
@@ -208,6 +309,52 @@ func (p *deploycmd) deploy(c *cli.Context) error {
 		return errors.New("App name must be provided, try `--app APP_NAME`")
 	}
 
+	if p.parallel < 1 {
+		return errors.New("--parallel must be at least 1")
+	}
+	if p.prune && !p.all {
+		return errors.New("--prune can only be used with --all")
+	}
+	if p.since != "" && !p.all {
+		return errors.New("--since can only be used with --all")
+	}
+	p.buildArgs, err = common.BuildArgs(c)
+	if err != nil {
+		return err
+	}
+	p.secrets, err = common.BuildSecrets(c)
+	if err != nil {
+		return err
+	}
+	if p.fromArchive != "" {
+		if p.all {
+			return errors.New("--from-archive cannot be used with --all")
+		}
+		p.archive, err = common.ReadImageArchive(p.fromArchive)
+		if err != nil {
+			return err
+		}
+	}
+	if !c.IsSet("pin-digest") {
+		// images are only pinned by default when they are pushed
+		p.pinDigest = viper.GetBool(config.PinImageDigest) && !p.local
+	}
+	if p.pinDigest && p.local {
+		return errors.New("--pin-digest cannot be used with --local, images only have a digest once pushed")
+	}
+
+	p.state, err = common.LoadDeployState(dir)
+	if err != nil {
+		return err
+	}
+
+	p.stdout = os.Stdout
+	p.out = os.Stdout
+	p.stdin = os.Stdin
+	if p.jsonOutput() && !p.dryRun {
+		p.out = os.Stderr
+	}
+
 	// appfApp is used to create/update app, with app file additions if provided
 	appfApp := models.App{
 		Name: appName,
@@ -217,15 +364,19 @@ func (p *deploycmd) deploy(c *cli.Context) error {
 		appfApp.Config = appf.Config
 		appfApp.Annotations = appf.Annotations
 		if appf.SyslogURL != "" {
-			// TODO consistent with some other fields (config), unsetting in app.yaml doesn't unset on server. undecided policy for all fields
+			// unsetting fields in app.yaml only unsets them on the server with --sync
 			appfApp.SyslogURL = &appf.SyslogURL
 		}
 	}
 
+	if p.dryRun {
+		return p.planDeploy(c, &appfApp, appf != nil)
+	}
+
 	// find and create/update app if required
 	app, err := apps.GetAppByName(p.clientV2, appName)
 	if _, ok := err.(apps.NameNotFoundError); ok && p.createApp {
-		app, err = apps.CreateApp(p.clientV2, &appfApp)
+		app, err = apps.CreateAppTo(p.out, p.clientV2, &appfApp)
 		if err != nil {
 			return err
 		}
@@ -233,6 +384,9 @@ func (p *deploycmd) deploy(c *cli.Context) error {
 		return err
 	} else if appf != nil {
 		// app exists, but we need to update it if we have an app file
+		if p.sync {
+			syncApp(p.out, &appfApp, app)
+		}
 		app, err = apps.PutApp(p.clientV2, app.ID, &appfApp)
 		if err != nil {
 			return fmt.Errorf("Failed to update app config: %v", err)
@@ -253,35 +407,61 @@ func (p *deploycmd) deploy(c *cli.Context) error {
 // deploySingle deploys a single function, either the current directory or if in the context
 // of an app and user provides relative path as the first arg, it will deploy that function.
 func (p *deploycmd) deploySingle(c *cli.Context, app *models.App) error {
-	var dir string
-	wd := common.GetWd()
-
-	if c.String("working-dir") != "" {
-		dir = c.String("working-dir")
-	} else {
-		// if we're in the context of an app, first arg is path to the function
-		path := c.Args().First()
-		if path != "" {
-			fmt.Printf("Deploying function at: ./%s\n", path)
-		}
-		dir = filepath.Join(wd, path)
-	}
-
-	err := os.Chdir(dir)
+	funcs, err := p.findFuncs(c)
 	if err != nil {
 		return err
 	}
-	defer os.Chdir(wd)
+	f := funcs[0]
+	r := newDeployResult(f)
+	r.attempted = true
+	r.err = p.deployFuncV20180708(c, app, f.path, f.ff, r, common.BuildOutput{Stdout: p.out, Stderr: os.Stderr})
+	if p.jsonOutput() {
+		if err := p.printDeployReport(c, app, []*deployResult{r}); err != nil {
+			return err
+		}
+	}
+	return r.err
+}
 
-	fpath, ff, err := common.FindAndParseFuncFileV20180708(dir)
-	if err != nil {
-		return err
+// funcToDeploy is a function found in the app tree by findFuncs
+type funcToDeploy struct {
+	path string
+	ff   *common.FuncFileV20180708
+}
+
+// deployResult is the outcome of deploying a single function
+type deployResult struct {
+	name      string
+	path      string
+	attempted bool
+	skipped   bool
+	created   bool
+	err       error
+
+	version   string
+	image     string
+	digest    string
+	durations map[string]time.Duration
+}
+
+func newDeployResult(f funcToDeploy) *deployResult {
+	return &deployResult{
+		name:      f.ff.Name,
+		path:      f.path,
+		version:   f.ff.Version,
+		image:     f.ff.ImageNameV20180708(),
+		durations: map[string]time.Duration{},
 	}
-	return p.deployFuncV20180708(c, app, fpath, ff)
 }
 
-// deployAll deploys all functions in an app.
-func (p *deploycmd) deployAll(c *cli.Context, app *models.App) error {
+// phaseDone records how long a deploy phase that began at start took
+func (r *deployResult) phaseDone(phase string, start time.Time) {
+	r.durations[phase] = time.Since(start)
+}
+
+// findFuncs returns the function to deploy, or with --all every function in the app tree. Functions
+// without a name in their func file are named after their directory.
+func (p *deploycmd) findFuncs(c *cli.Context) ([]funcToDeploy, error) {
 	var dir string
 	wd := common.GetWd()
 
@@ -290,112 +470,404 @@ func (p *deploycmd) deployAll(c *cli.Context, app *models.App) error {
 	} else {
 		// if we're in the context of an app, first arg is path to the function
 		path := c.Args().First()
-		if path != "" {
-			fmt.Printf("Deploying function at: ./%s\n", path)
+		if path != "" && !p.dryRun {
+			fmt.Fprintf(p.out, "Deploying function at: ./%s\n", path)
 		}
 		dir = filepath.Join(wd, path)
 	}
 
-	var funcFound bool
+	if !p.all {
+		fpath, ff, err := common.FindAndParseFuncFileV20180708(dir)
+		if err != nil {
+			return nil, err
+		}
+		if ff.Name == "" {
+			ff.Name = filepath.Base(filepath.Dir(fpath))
+		}
+		if p.platform != "" {
+			ff.Platforms = common.ParsePlatforms(p.platform)
+		}
+		return []funcToDeploy{{path: fpath, ff: ff}}, nil
+	}
+
+	var funcs []funcToDeploy
 	err := common.WalkFuncsV20180708(dir, func(path string, ff *common.FuncFileV20180708, err error) error {
 		if err != nil { // probably some issue with funcfile parsing, can decide to handle this differently if we'd like
 			return err
 		}
-		dir := filepath.Dir(path)
-		if dir != wd {
-			// change dirs
-			err = os.Chdir(dir)
-			if err != nil {
-				return err
-			}
-		}
-		p2 := strings.TrimPrefix(dir, wd)
+		p2 := strings.TrimPrefix(filepath.Dir(path), wd)
 		if ff.Name == "" {
 			ff.Name = strings.Replace(p2, "/", "-", -1)
 			if strings.HasPrefix(ff.Name, "-") {
 				ff.Name = ff.Name[1:]
 			}
 		}
+		if p.platform != "" {
+			ff.Platforms = common.ParsePlatforms(p.platform)
+		}
+		funcs = append(funcs, funcToDeploy{path: path, ff: ff})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		err = p.deployFuncV20180708(c, app, path, ff)
+	if p.since != "" {
+		p.sinceChanged, err = changedFuncsSince(dir, p.since, funcs, p.sharedPaths)
 		if err != nil {
-			return fmt.Errorf("deploy error on %s: %v", path, err)
+			return nil, err
 		}
+	}
+	return funcs, nil
+}
 
-		now := time.Now()
-		os.Chtimes(path, now, now)
-		funcFound = true
-		return nil
-	})
+// deployAll deploys all functions in an app. Functions are deployed p.parallel at a time,
+// once a function fails no further functions are started but those in flight are left to finish.
+func (p *deploycmd) deployAll(c *cli.Context, app *models.App) error {
+	funcs, err := p.findFuncs(c)
 	if err != nil {
 		return err
 	}
 
-	if !funcFound {
+	if len(funcs) == 0 {
 		return errors.New("No functions found to deploy")
 	}
 
+	results := make([]*deployResult, len(funcs))
+	for i, f := range funcs {
+		results[i] = newDeployResult(f)
+	}
+	runDeploys(funcs, results, p.parallel, func(f funcToDeploy, r *deployResult) error {
+		out := common.BuildOutput{Stdout: p.out, Stderr: os.Stderr}
+		if p.parallel > 1 {
+			// lines of concurrent deploys are told apart by the function they are from
+			stdout, stderr := newLinePrefixWriter(p.out, f.ff.Name+": "), newLinePrefixWriter(os.Stderr, f.ff.Name+": ")
+			defer stdout.Flush()
+			defer stderr.Flush()
+			out = common.BuildOutput{Stdout: stdout, Stderr: stderr, Concurrent: true}
+		}
+
+		changed, err := p.funcChanged(c, app, f.path, f.ff)
+		if err == nil && changed {
+			err = p.deployFuncV20180708(c, app, f.path, f.ff, r, out)
+		} else if err == nil && p.since != "" && !p.sinceChanged[f.path] {
+			fmt.Fprintf(out.Stdout, "Skipping %s, unchanged since %s\n", f.ff.Name, p.since)
+		} else if err == nil {
+			fmt.Fprintf(out.Stdout, "Skipping %s, unchanged since its last deploy (use --force to deploy anyway)\n", f.ff.Name)
+		}
+		r.name = f.ff.Name
+		r.skipped = !changed
+		return err
+	})
+
+	if p.jsonOutput() {
+		if err := p.printDeployReport(c, app, results); err != nil {
+			return err
+		}
+	}
+	if err := p.reportDeployResults(results); err != nil {
+		return err
+	}
+	if p.prune {
+		return p.pruneApp(c, app, funcs)
+	}
 	return nil
 }
 
-func (p *deploycmd) deployFuncV20180708(c *cli.Context, app *models.App, funcfilePath string, funcfile *common.FuncFileV20180708) error {
+// runDeploys calls deploy for each function, parallel at a time, recording the outcome in the result of the
+// function. Once a deploy fails no further deploys are started, but those in flight are left to finish.
+func runDeploys(funcs []funcToDeploy, results []*deployResult, parallel int, deploy func(f funcToDeploy, r *deployResult) error) {
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := false
+	for i, f := range funcs {
+		sem <- struct{}{}
+		mu.Lock()
+		stop := failed
+		mu.Unlock()
+		if stop {
+			<-sem
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *deployResult, f funcToDeploy) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			err := deploy(f, r)
+
+			mu.Lock()
+			defer mu.Unlock()
+			r.attempted = true
+			r.err = err
+			if err != nil {
+				failed = true
+			}
+		}(results[i], f)
+	}
+	wg.Wait()
+}
+
+// linePrefixWriter writes lines to w with prefix before each of them. Only whole lines are written, so that the
+// lines of writers used concurrently are not mixed up, what's left of the last line is written by Flush.
+type linePrefixWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func newLinePrefixWriter(w io.Writer, prefix string) *linePrefixWriter {
+	return &linePrefixWriter{w: w, prefix: prefix}
+}
+
+func (w *linePrefixWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := w.w.Write(append([]byte(w.prefix), w.buf[:i+1]...)); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes what has been written since the last line, as a line of its own
+func (w *linePrefixWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.w.Write(append(append([]byte(w.prefix), w.buf...), '\n'))
+	w.buf = nil
+	return err
+}
+
+// reportDeployResults summarises the outcome of deployAll when functions were deployed concurrently
+// and returns an error if any function failed to deploy
+func (p *deploycmd) reportDeployResults(results []*deployResult) error {
+	var failures []*deployResult
+	for _, r := range results {
+		if r.err != nil {
+			failures = append(failures, r)
+		}
+	}
+
+	if p.parallel > 1 && !p.jsonOutput() {
+		fmt.Fprintln(p.out, "\nDeploy summary:")
+		for _, r := range results {
+			switch {
+			case !r.attempted:
+				fmt.Fprintf(p.out, "  %s: %s\n", r.name, color.YellowString("not deployed"))
+			case r.err != nil:
+				fmt.Fprintf(p.out, "  %s: %s (%v)\n", r.name, color.RedString("failed"), r.err)
+			case r.skipped:
+				fmt.Fprintf(p.out, "  %s: %s\n", r.name, "unchanged")
+			default:
+				fmt.Fprintf(p.out, "  %s: %s\n", r.name, color.GreenString("deployed"))
+			}
+		}
+	}
+
+	switch len(failures) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("deploy error on %s: %v", failures[0].path, failures[0].err)
+	default:
+		return fmt.Errorf("%d of %d functions failed to deploy", len(failures), len(results))
+	}
+}
+
+// deployFuncV20180708 bumps, builds, pushes and updates a function, recording the outcome of each phase in r
+func (p *deploycmd) deployFuncV20180708(c *cli.Context, app *models.App, funcfilePath string, funcfile *common.FuncFileV20180708, r *deployResult, out common.BuildOutput) error {
 	if funcfile.Name == "" {
 		funcfile.Name = filepath.Base(filepath.Dir(funcfilePath)) // todo: should probably make a copy of ff before changing it
 	}
-	fmt.Printf("Deploying %s to app: %s\n", funcfile.Name, app.Name)
+	fmt.Fprintf(out.Stdout, "Deploying %s to app: %s\n", funcfile.Name, app.Name)
+
+	if p.archive != nil {
+		return p.deployArchiveV20180708(c, app, funcfilePath, funcfile, r, out)
+	}
 
 	var err error
 	if !p.noBump {
-		funcfile2, err := common.BumpItV20180708(funcfilePath, common.Patch)
+		start := time.Now()
+		funcfile2, err := common.BumpItV20180708To(out.Stdout, funcfilePath, common.Patch)
 		if err != nil {
 			return err
 		}
 		funcfile.Version = funcfile2.Version
+		r.version = funcfile.Version
+		r.image = funcfile.ImageNameV20180708()
+		r.phaseDone("bump", start)
 		// TODO: this whole funcfile handling needs some love, way too confusing. Only bump makes permanent changes to it.
 	}
 
-	buildArgs := c.StringSlice("build-arg")
-	_, err = common.BuildFuncV20180708(common.IsVerbose(), funcfilePath, funcfile, buildArgs, p.noCache)
+	engine, err := common.Engine()
 	if err != nil {
 		return err
 	}
+	// buildx pushes images as it builds them, as images for other platforms can't be loaded into docker
+	buildxPush := len(funcfile.Platforms) > 0 && engine.Buildx && !p.local
+	if buildxPush {
+		if err := common.ValidateFullImageName(funcfile.ImageNameV20180708()); err != nil {
+			return err
+		}
+	} else if p.local && len(funcfile.Platforms) > 1 {
+		return fmt.Errorf("%s is built for more than one platform and cannot be deployed with --local", funcfile.Name)
+	}
 
-	if !p.local {
-		if err := common.DockerPushV20180708(funcfile); err != nil {
+	start := time.Now()
+	_, err = common.BuildFuncV20180708(out, common.IsVerbose(), funcfilePath, funcfile, p.buildArgs, p.secrets, p.noCache, buildxPush)
+	if err != nil {
+		return err
+	}
+	r.phaseDone("build", start)
+
+	return p.pushAndUpdate(c, app, funcfilePath, funcfile, r, out, buildxPush)
+}
+
+// deployArchiveV20180708 loads the image of the --from-archive archive, tagged with the version it was built
+// with, then pushes it and updates the function as deployFuncV20180708 does
+func (p *deploycmd) deployArchiveV20180708(c *cli.Context, app *models.App, funcfilePath string, funcfile *common.FuncFileV20180708, r *deployResult, out common.BuildOutput) error {
+	if p.archive.Name != funcfile.Name {
+		return fmt.Errorf("archive %s holds function %s, not %s", p.fromArchive, p.archive.Name, funcfile.Name)
+	}
+	funcfile.Version = p.archive.Version
+	r.version = funcfile.Version
+	r.image = funcfile.ImageNameV20180708()
+
+	start := time.Now()
+	if err := common.LoadImageArchive(out.Stdout, p.fromArchive, funcfile.ImageNameV20180708()); err != nil {
+		return err
+	}
+	r.phaseDone("load", start)
+
+	return p.pushAndUpdate(c, app, funcfilePath, funcfile, r, out, false)
+}
+
+// pushAndUpdate pushes the image of the function, unless it's deployed with --local or buildx already pushed
+// it as it built it, then signs it and updates the function
+func (p *deploycmd) pushAndUpdate(c *cli.Context, app *models.App, funcfilePath string, funcfile *common.FuncFileV20180708, r *deployResult, out common.BuildOutput, buildxPushed bool) error {
+	var err error
+	if !p.local && !buildxPushed {
+		start := time.Now()
+		if err := common.DockerPushV20180708To(out, funcfile); err != nil {
+			return err
+		}
+		r.phaseDone("push", start)
+	}
+
+	start := time.Now()
+	if err := p.signImage(out.Stdout, funcfile, buildxPushed); err != nil {
+		return err
+	}
+	r.phaseDone("sign", start)
+
+	image := funcfile.ImageNameV20180708()
+	if !p.local && (p.pinDigest || p.jsonOutput()) {
+		repoDigest := common.ImageRepoDigest
+		if buildxPushed {
+			repoDigest = common.ManifestDigest
+		}
+		digest, err := repoDigest(image)
+		if err != nil && p.pinDigest {
 			return err
 		}
+		r.digest = digest
+		if p.pinDigest {
+			image = digest
+		}
 	}
 
-	if err := p.signImage(funcfile); err != nil {
+	start = time.Now()
+	if r.created, err = p.updateFunction(c, app, funcfile, image, out.Stdout); err != nil {
 		return err
 	}
+	r.phaseDone("update", start)
 
-	return p.updateFunction(c, app.ID, funcfile)
+	return p.recordDeploy(c, app, funcfilePath, funcfile)
 }
 
-func (p *deploycmd) updateFunction(c *cli.Context, appID string, ff *common.FuncFileV20180708) error {
-	fmt.Printf("Updating function %s using image %s...\n", ff.Name, ff.ImageNameV20180708())
+// funcChanged reports whether a function has changes since the --since ref, if set, and differs from what was
+// last deployed to the app in the current context
+func (p *deploycmd) funcChanged(c *cli.Context, app *models.App, funcfilePath string, funcfile *common.FuncFileV20180708) (bool, error) {
+	if p.since != "" && !p.sinceChanged[funcfilePath] {
+		return false, nil
+	}
+	if p.force {
+		return true, nil
+	}
+	last := p.state.Get(app.Name, funcfile.Name)
+	if last == nil {
+		return true, nil
+	}
+	digest, err := common.FuncDigestV20180708(funcfilePath, funcfile, p.buildArgs)
+	if err != nil {
+		return false, err
+	}
+	return digest != last.Digest, nil
+}
+
+// recordDeploy saves the digest of a deployed function so that unchanged functions can be skipped next time
+func (p *deploycmd) recordDeploy(c *cli.Context, app *models.App, funcfilePath string, funcfile *common.FuncFileV20180708) error {
+	digest, err := common.FuncDigestV20180708(funcfilePath, funcfile, p.buildArgs)
+	if err != nil {
+		return err
+	}
+	return p.state.Set(app.Name, funcfile.Name, &common.FuncDeployState{
+		Digest:     digest,
+		Image:      funcfile.ImageNameV20180708(),
+		DeployedAt: time.Now(),
+	})
+}
+
+// updateFunction creates or updates the function in app from its func file, running image. image is either
+// the tag of the func file or, when pinned, the digest of the image the tag pointed to. It reports
+// whether the function was created. What it changes is printed to out.
+func (p *deploycmd) updateFunction(c *cli.Context, app *models.App, ff *common.FuncFileV20180708, image string, out io.Writer) (bool, error) {
+	appID := app.ID
+	fmt.Fprintf(out, "Updating function %s using image %s...\n", ff.Name, image)
 
 	fn := &models.Fn{}
 	if err := function.WithFuncFileV20180708(ff, fn); err != nil {
-		return fmt.Errorf("Error getting function with funcfile: %s", err)
+		return false, fmt.Errorf("Error getting function with funcfile: %s", err)
 	}
 
 	fnRes, err := function.GetFnByName(p.clientV2, appID, ff.Name)
+	fn.Annotations = withImageTag(fn.Annotations, fnRes, image, fn.Image)
+	fn.Image = image
 	if _, ok := err.(function.NameNotFoundError); ok {
 		fn.Name = ff.Name
-		fn, err = function.CreateFn(p.clientV2, appID, fn)
+		fn, err = function.CreateFnTo(out, p.clientV2, appID, fn)
 		if err != nil {
-			return err
+			return false, err
 		}
 	} else if err != nil {
 		// probably service is down or something...
-		return err
+		return false, err
 	} else {
 		fn.ID = fnRes.ID
+		if p.sync {
+			syncFn(out, fn, fnRes)
+		}
 		err = function.PutFn(p.clientV2, fn.ID, fn)
 		if err != nil {
-			return err
+			return false, err
+		}
+		if err := p.state.AddRevision(app.Name, ff.Name, common.NewFuncRevision(fnRes)); err != nil {
+			return false, fmt.Errorf("could not record previous revision of %s: %v", ff.Name, err)
 		}
 	}
 
@@ -411,22 +883,22 @@ func (p *deploycmd) updateFunction(c *cli.Context, appID string, ff *common.Func
 
 			trigs, err := trigger.GetTriggerByName(p.clientV2, appID, fn.ID, t.Name)
 			if _, ok := err.(trigger.NameNotFoundError); ok {
-				err = trigger.CreateTrigger(p.clientV2, trig)
+				err = trigger.CreateTriggerTo(out, p.clientV2, trig)
 				if err != nil {
-					return err
+					return false, err
 				}
 			} else if err != nil {
-				return err
+				return false, err
 			} else {
 				trig.ID = trigs.ID
 				err = trigger.PutTrigger(p.clientV2, trig)
 				if err != nil {
-					return err
+					return false, err
 				}
 			}
 		}
 	}
-	return nil
+	return fnRes == nil, nil
 }
 
 func (p *deploycmd) getOracleProvider() (*oracle.OracleProvider, error) {
@@ -440,7 +912,27 @@ func (p *deploycmd) getOracleProvider() (*oracle.OracleProvider, error) {
 	return nil, nil
 }
 
-func (p *deploycmd) signImage(funcfile *common.FuncFileV20180708) error {
+// withImageTag returns annotations recording tag when image is pinned to a digest, or removing the tag
+// recorded by an earlier pinned deploy of existing when it is not. existing is nil for a new function.
+func withImageTag(annotations map[string]interface{}, existing *models.Fn, image, tag string) map[string]interface{} {
+	if image == tag {
+		if existing == nil {
+			return annotations
+		}
+		if _, ok := existing.Annotations[common.ImageTagAnnotation]; !ok {
+			return annotations
+		}
+		tag = ""
+	}
+	res := make(map[string]interface{}, len(annotations)+1)
+	for k, v := range annotations {
+		res[k] = v
+	}
+	res[common.ImageTagAnnotation] = tag
+	return res
+}
+
+func (p *deploycmd) signImage(out io.Writer, funcfile *common.FuncFileV20180708, buildxPushed bool) error {
 	signingDetails := funcfile.SigningDetails
 	signatureConfigured, err := isSignatureConfigured(signingDetails)
 	if err != nil {
@@ -453,17 +945,17 @@ func (p *deploycmd) signImage(funcfile *common.FuncFileV20180708) error {
 	if oracleProvider == nil {
 		return nil
 	}
-	fmt.Printf("Signing image %s using KmsKey %s...\n", funcfile.ImageNameV20180708(), signingDetails.KmsKeyId)
-	imageDigest, err := getImageDigest(funcfile)
+	fmt.Fprintf(out, "Signing image %s using KmsKey %s...\n", funcfile.ImageNameV20180708(), signingDetails.KmsKeyId)
+	imageDigest, err := getImageDigest(out, funcfile, buildxPushed)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Image digest is %s\n", imageDigest)
+	fmt.Fprintf(out, "Image digest is %s\n", imageDigest)
 	repositoryName, err := getRepositoryName(funcfile)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Image belongs to repository %s\n", repositoryName)
+	fmt.Fprintf(out, "Image belongs to repository %s\n", repositoryName)
 	artifactsClient, err := artifacts.NewArtifactsClientWithConfigurationProvider(oracleProvider.ConfigurationProvider)
 	if err != nil {
 		return err
@@ -479,7 +971,7 @@ func (p *deploycmd) signImage(funcfile *common.FuncFileV20180708) error {
 		return err
 	}
 	if !signatureRequired {
-		fmt.Printf("Image %s is already signed by %s\n", funcfile.ImageNameV20180708(), signingDetails.KmsKeyId)
+		fmt.Fprintf(out, "Image %s is already signed by %s\n", funcfile.ImageNameV20180708(), signingDetails.KmsKeyId)
 		return nil
 	}
 	message, signature, err := createImageSignature(oracleProvider, region, imageDigest, repositoryName, funcfile.SigningDetails)
@@ -487,7 +979,7 @@ func (p *deploycmd) signImage(funcfile *common.FuncFileV20180708) error {
 		return err
 	}
 	if err = uploadImageSignature(artifactsClient, compartmentId, imageId, message, signature, funcfile.SigningDetails); err == nil {
-		fmt.Printf("Successfully signed and uploaded image signature for %s\n", funcfile.ImageNameV20180708())
+		fmt.Fprintf(out, "Successfully signed and uploaded image signature for %s\n", funcfile.ImageNameV20180708())
 	}
 	return err
 }
@@ -528,14 +1020,26 @@ func getRepositoryName(ff *common.FuncFileV20180708) (string, error) {
 	return parts[3], nil
 }
 
-func getImageDigest(ff *common.FuncFileV20180708) (string, error) {
-	fmt.Printf("Fetching image digest for %s\n", ff.ImageNameV20180708())
+func getImageDigest(out io.Writer, ff *common.FuncFileV20180708, buildxPushed bool) (string, error) {
+	fmt.Fprintf(out, "Fetching image digest for %s\n", ff.ImageNameV20180708())
+	if buildxPushed {
+		// images built by buildx are only in the registry
+		digest, err := common.ManifestDigest(ff.ImageNameV20180708())
+		if err != nil {
+			return "", err
+		}
+		return digest[strings.Index(digest, "@")+1:], nil
+	}
 	parts := strings.Split(ff.ImageNameV20180708(), ":")
 	if len(parts) < 2 {
 		return "", fmt.Errorf("failed to parse image %s", ff.ImageNameV20180708())
 	}
 	image, tag := parts[0], parts[1]
-	imageDigests, err := exec.Command("docker", "images", "--digests", image, "--format", "{{.Tag}} {{.Digest}}").Output()
+	engine, err := common.Engine()
+	if err != nil {
+		return "", err
+	}
+	imageDigests, err := engine.Command("images", "--digests", image, "--format", "{{.Tag}} {{.Digest}}").Output()
 	if err != nil {
 		return "", fmt.Errorf("error while listing image digests for %s, %s", ff.ImageNameV20180708(), err)
 	}
@@ -0,0 +1,333 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	common "github.com/fnproject/cli/common"
+	apps "github.com/fnproject/cli/objects/app"
+	function "github.com/fnproject/cli/objects/fn"
+	trigger "github.com/fnproject/cli/objects/trigger"
+	models "github.com/fnproject/fn_go/modelsv2"
+	"github.com/urfave/cli"
+)
+
+// Actions a deploy plan can take on a resource
+const (
+	planCreate = "create"
+	planUpdate = "update"
+	planNone   = "none"
+	planSkip   = "skip"
+	planDelete = "delete"
+)
+
+// fieldChange is a single field a deploy would change on the server
+type fieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// resourcePlan is what a deploy would do to a single app, function or trigger
+type resourcePlan struct {
+	Name    string        `json:"name"`
+	Action  string        `json:"action"`
+	Changes []fieldChange `json:"changes,omitempty"`
+}
+
+// fnPlan is what a deploy would do to a function and its triggers
+type fnPlan struct {
+	resourcePlan
+	Path     string         `json:"path"`
+	Triggers []resourcePlan `json:"triggers,omitempty"`
+}
+
+// deployPlan is everything a deploy would create or update on the server
+type deployPlan struct {
+	App       resourcePlan `json:"app"`
+	Functions []fnPlan     `json:"functions"`
+}
+
+// planDeploy works out what a deploy would change, using only reads against the server, and
+// prints the plan as text or, with --output json, as JSON.
+func (p *deploycmd) planDeploy(c *cli.Context, appfApp *models.App, hasAppFile bool) error {
+	plan := &deployPlan{}
+
+	app, err := apps.GetAppByName(p.clientV2, appfApp.Name)
+	if _, ok := err.(apps.NameNotFoundError); ok && p.createApp {
+		app = nil
+		plan.App = resourcePlan{Name: appfApp.Name, Action: planCreate, Changes: appChanges(&models.App{}, appfApp)}
+	} else if err != nil {
+		return err
+	} else if hasAppFile {
+		changes := appChanges(app, appfApp)
+		if p.sync {
+			changes = append(changes, syncChanges(app.Config, appfApp.Config, app.Annotations, appfApp.Annotations)...)
+			if appfApp.SyslogURL == nil && app.SyslogURL != nil && *app.SyslogURL != "" {
+				changes = append(changes, fieldChange{Field: "syslog_url", Old: app.SyslogURL})
+			}
+		}
+		plan.App = newResourcePlan(appfApp.Name, planUpdate, changes)
+	} else {
+		plan.App = resourcePlan{Name: appfApp.Name, Action: planNone}
+	}
+
+	funcs, err := p.findFuncs(c)
+	if err != nil {
+		return err
+	}
+	for _, f := range funcs {
+		fp, err := p.planFunction(c, app, f)
+		if err != nil {
+			return fmt.Errorf("could not plan deploy of %s: %v", f.path, err)
+		}
+		plan.Functions = append(plan.Functions, *fp)
+	}
+
+	if p.prune && app != nil {
+		if err := p.planPrune(c, app, funcs, plan); err != nil {
+			return err
+		}
+	}
+
+	if strings.ToLower(p.output) == "json" {
+		enc := json.NewEncoder(p.stdout)
+		enc.SetIndent("", "    ")
+		return enc.Encode(plan)
+	}
+	printDeployPlan(p.stdout, plan)
+	return nil
+}
+
+// planFunction works out what a deploy would do to a function and its triggers, app is nil if the app
+// does not exist yet.
+func (p *deploycmd) planFunction(c *cli.Context, app *models.App, f funcToDeploy) (*fnPlan, error) {
+	ff := *f.ff
+	fp := &fnPlan{resourcePlan: resourcePlan{Name: ff.Name}, Path: f.path}
+
+	if p.all && app != nil {
+		changed, err := p.funcChanged(c, app, f.path, &ff)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			fp.Action = planSkip
+			return fp, nil
+		}
+	}
+
+	if !p.noBump {
+		version, err := common.NextVersionV20180708(&ff, common.Patch)
+		if err != nil {
+			return nil, err
+		}
+		ff.Version = version
+	}
+	desired := &models.Fn{}
+	if err := function.WithFuncFileV20180708(&ff, desired); err != nil {
+		return nil, err
+	}
+
+	var existing *models.Fn
+	if app != nil {
+		fn, err := function.GetFnByName(p.clientV2, app.ID, ff.Name)
+		if _, ok := err.(function.NameNotFoundError); !ok && err != nil {
+			return nil, err
+		}
+		existing = fn
+	}
+	if existing == nil {
+		fp.Action = planCreate
+		fp.Changes = fnChanges(&models.Fn{}, desired)
+	} else {
+		changes := fnChanges(existing, desired)
+		if p.sync {
+			changes = append(changes, syncChanges(existing.Config, desired.Config, existing.Annotations, desired.Annotations)...)
+		}
+		fp.resourcePlan = newResourcePlan(ff.Name, planUpdate, changes)
+	}
+
+	for _, t := range ff.Triggers {
+		desiredTrigger := &models.Trigger{Name: t.Name, Type: t.Type, Source: t.Source}
+		var existingTrigger *models.Trigger
+		if existing != nil {
+			trig, err := trigger.GetTriggerByName(p.clientV2, app.ID, existing.ID, t.Name)
+			if _, ok := err.(trigger.NameNotFoundError); !ok && err != nil {
+				return nil, err
+			}
+			existingTrigger = trig
+		}
+		if existingTrigger == nil {
+			fp.Triggers = append(fp.Triggers, resourcePlan{Name: t.Name, Action: planCreate,
+				Changes: triggerChanges(&models.Trigger{}, desiredTrigger)})
+		} else {
+			fp.Triggers = append(fp.Triggers, newResourcePlan(t.Name, planUpdate, triggerChanges(existingTrigger, desiredTrigger)))
+		}
+	}
+	return fp, nil
+}
+
+// planPrune adds the functions and triggers --prune would delete to plan
+func (p *deploycmd) planPrune(c *cli.Context, app *models.App, funcs []funcToDeploy, plan *deployPlan) error {
+	fns, triggers, err := p.pruneCandidates(c, app, funcs)
+	if err != nil {
+		return err
+	}
+
+	fnNames := map[string]string{}
+	all, err := common.ListFnsInApp(c, p.clientV2, app)
+	if err != nil {
+		return err
+	}
+	for _, fn := range all {
+		fnNames[fn.ID] = fn.Name
+	}
+	for _, fn := range fns {
+		plan.Functions = append(plan.Functions, fnPlan{resourcePlan: resourcePlan{Name: fn.Name, Action: planDelete}})
+	}
+	for _, t := range triggers {
+		for i := range plan.Functions {
+			if plan.Functions[i].Name == fnNames[t.FnID] {
+				plan.Functions[i].Triggers = append(plan.Functions[i].Triggers, resourcePlan{Name: t.Name, Action: planDelete})
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// newResourcePlan returns a plan with the given action, or no action if nothing would change
+func newResourcePlan(name, action string, changes []fieldChange) resourcePlan {
+	if len(changes) == 0 {
+		action = planNone
+	}
+	return resourcePlan{Name: name, Action: action, Changes: changes}
+}
+
+// appChanges returns the fields updating existing with desired would change. As with PutApp, config
+// and annotations are merged rather than replaced.
+func appChanges(existing, desired *models.App) []fieldChange {
+	var changes []fieldChange
+	changes = appendMapChanges(changes, "config", stringMap(existing.Config), stringMap(desired.Config))
+	changes = appendMapChanges(changes, "annotations", existing.Annotations, desired.Annotations)
+	if desired.SyslogURL != nil && (existing.SyslogURL == nil || *existing.SyslogURL != *desired.SyslogURL) {
+		changes = append(changes, fieldChange{Field: "syslog_url", Old: existing.SyslogURL, New: desired.SyslogURL})
+	}
+	return changes
+}
+
+// fnChanges returns the fields updating existing with desired would change. Unset fields in desired are
+// left alone by PutFn so they are not reported.
+func fnChanges(existing, desired *models.Fn) []fieldChange {
+	var changes []fieldChange
+	if desired.Image != "" && desired.Image != existing.Image {
+		changes = append(changes, fieldChange{Field: "image", Old: existing.Image, New: desired.Image})
+	}
+	if desired.Memory != 0 && desired.Memory != existing.Memory {
+		changes = append(changes, fieldChange{Field: "memory", Old: existing.Memory, New: desired.Memory})
+	}
+	if desired.Timeout != nil && (existing.Timeout == nil || *existing.Timeout != *desired.Timeout) {
+		changes = append(changes, fieldChange{Field: "timeout", Old: existing.Timeout, New: desired.Timeout})
+	}
+	if desired.IdleTimeout != nil && (existing.IdleTimeout == nil || *existing.IdleTimeout != *desired.IdleTimeout) {
+		changes = append(changes, fieldChange{Field: "idle_timeout", Old: existing.IdleTimeout, New: desired.IdleTimeout})
+	}
+	changes = appendMapChanges(changes, "config", stringMap(existing.Config), stringMap(desired.Config))
+	changes = appendMapChanges(changes, "annotations", existing.Annotations, desired.Annotations)
+	return changes
+}
+
+func triggerChanges(existing, desired *models.Trigger) []fieldChange {
+	var changes []fieldChange
+	if desired.Type != existing.Type {
+		changes = append(changes, fieldChange{Field: "type", Old: existing.Type, New: desired.Type})
+	}
+	if desired.Source != existing.Source {
+		changes = append(changes, fieldChange{Field: "source", Old: existing.Source, New: desired.Source})
+	}
+	return changes
+}
+
+func stringMap(m map[string]string) map[string]interface{} {
+	res := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		res[k] = v
+	}
+	return res
+}
+
+// appendMapChanges adds a change for each key in desired whose value differs from existing. Values are
+// compared by their JSON encoding as the server and the func file decode them to different types.
+func appendMapChanges(changes []fieldChange, field string, existing, desired map[string]interface{}) []fieldChange {
+	keys := make([]string, 0, len(desired))
+	for k := range desired {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		old, ok := existing[k]
+		if ok && jsonEqual(old, desired[k]) {
+			continue
+		}
+		changes = append(changes, fieldChange{Field: field + "." + k, Old: old, New: desired[k]})
+	}
+	return changes
+}
+
+func jsonEqual(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
+
+func printDeployPlan(w io.Writer, plan *deployPlan) {
+	fmt.Fprintln(w, "Deploy plan, nothing has been changed:")
+	printResourcePlan(w, "", "app", plan.App)
+	for _, f := range plan.Functions {
+		printResourcePlan(w, "", "function", f.resourcePlan)
+		for _, t := range f.Triggers {
+			printResourcePlan(w, "    ", "trigger", t)
+		}
+	}
+}
+
+func printResourcePlan(w io.Writer, indent, kind string, rp resourcePlan) {
+	fmt.Fprintf(w, "%s%s %s: %s\n", indent, kind, rp.Name, rp.Action)
+	for _, ch := range rp.Changes {
+		fmt.Fprintf(w, "%s    %s: %s -> %s\n", indent, ch.Field, planValue(ch.Old), planValue(ch.New))
+	}
+}
+
+func planValue(v interface{}) string {
+	if v == nil {
+		return "<unset>"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if string(b) == "null" {
+		return "<unset>"
+	}
+	return string(b)
+}
@@ -0,0 +1,223 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/cli/common"
+	"github.com/fnproject/cli/config"
+	models "github.com/fnproject/fn_go/modelsv2"
+	"github.com/spf13/viper"
+	"github.com/urfave/cli"
+)
+
+func TestAppChanges(t *testing.T) {
+	syslog, otherSyslog := "tcp://logs:514", "tcp://other:514"
+	existing := &models.App{
+		Config:      map[string]string{"SAME": "1", "CHANGED": "1", "KEPT": "1"},
+		Annotations: map[string]interface{}{"count": float64(1)},
+		SyslogURL:   &syslog,
+	}
+
+	// config and annotations are merged, so keys missing from desired aren't changes, and numbers decoded
+	// as different types are equal
+	changes := appChanges(existing, &models.App{
+		Config:      map[string]string{"SAME": "1", "CHANGED": "2", "ADDED": "3"},
+		Annotations: map[string]interface{}{"count": 1},
+		SyslogURL:   &otherSyslog,
+	})
+	expected := []fieldChange{
+		{Field: "config.ADDED", Old: nil, New: "3"},
+		{Field: "config.CHANGED", Old: "1", New: "2"},
+		{Field: "syslog_url", Old: &syslog, New: &otherSyslog},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+
+	if changes := appChanges(existing, &models.App{}); len(changes) != 0 {
+		t.Errorf("expected an app without fields to change nothing, got %v", changes)
+	}
+}
+
+func TestFnChanges(t *testing.T) {
+	timeout, otherTimeout := int32(30), int32(60)
+	existing := &models.Fn{
+		Image:   "registry/hello:0.0.1",
+		Memory:  128,
+		Timeout: &timeout,
+		Config:  map[string]string{"A": "1"},
+	}
+
+	changes := fnChanges(existing, &models.Fn{
+		Image:   "registry/hello:0.0.2",
+		Memory:  256,
+		Timeout: &otherTimeout,
+		Config:  map[string]string{"A": "1"},
+	})
+	expected := []fieldChange{
+		{Field: "image", Old: "registry/hello:0.0.1", New: "registry/hello:0.0.2"},
+		{Field: "memory", Old: uint64(128), New: uint64(256)},
+		{Field: "timeout", Old: &timeout, New: &otherTimeout},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+
+	// PutFn leaves unset fields alone
+	if changes := fnChanges(existing, &models.Fn{Image: existing.Image}); len(changes) != 0 {
+		t.Errorf("expected unset fields not to be changes, got %v", changes)
+	}
+}
+
+func TestTriggerChanges(t *testing.T) {
+	existing := &models.Trigger{Name: "t", Type: "http", Source: "/hello"}
+	if changes := triggerChanges(existing, &models.Trigger{Name: "t", Type: "http", Source: "/hello"}); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+	expected := []fieldChange{{Field: "source", Old: "/hello", New: "/hi"}}
+	if changes := triggerChanges(existing, &models.Trigger{Name: "t", Type: "http", Source: "/hi"}); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+}
+
+// testPlanDeploy plans a deploy --all --sync --prune of an app tree with a changed function and a new one to
+// a server with the app, the changed function and a function no longer in the tree, returning what it printed
+func testPlanDeploy(t *testing.T, output string) string {
+	registry := viper.GetString(config.EnvFnRegistry)
+	viper.Set(config.EnvFnRegistry, "registry")
+	defer viper.Set(config.EnvFnRegistry, registry)
+
+	dir, err := ioutil.TempDir("", "deploy-plan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"hello/func.yaml": `schema_version: 20180708
+name: hello
+version: 0.0.1
+memory: 256
+config:
+  A: "1"
+triggers:
+- name: hello-trigger
+  type: http
+  source: /hi
+`,
+		"new/func.yaml": `schema_version: 20180708
+name: new
+version: 0.0.1
+triggers:
+- name: new-trigger
+  type: http
+  source: /new
+`,
+	})
+
+	_, client := newFakeFnServer(t,
+		[]*models.App{{ID: "app1", Name: "app", Config: map[string]string{"A": "1", "OLD": "x"}}},
+		[]*models.Fn{
+			{ID: "fn1", AppID: "app1", Name: "hello", Image: "registry/hello:0.0.1", Memory: 128, Config: map[string]string{"A": "1"}},
+			{ID: "fn2", AppID: "app1", Name: "gone", Image: "registry/gone:0.0.1"},
+		},
+		[]*models.Trigger{
+			{ID: "t1", AppID: "app1", FnID: "fn1", Name: "hello-trigger", Type: "http", Source: "/hello"},
+			{ID: "t2", AppID: "app1", FnID: "fn2", Name: "gone-trigger", Type: "http", Source: "/gone"},
+		})
+	state, err := common.LoadDeployState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	p := &deploycmd{clientV2: client, all: true, sync: true, prune: true, dryRun: true, output: output,
+		state: state, stdout: &stdout, out: ioutil.Discard}
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.String("working-dir", dir, "")
+	c := cli.NewContext(cli.NewApp(), flags, nil)
+	if err := p.planDeploy(c, &models.App{Name: "app", Config: map[string]string{"A": "2"}}, true); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Replace(stdout.String(), dir, "DIR", -1)
+}
+
+func TestPlanDeploy(t *testing.T) {
+	expected := `Deploy plan, nothing has been changed:
+app app: update
+    config.A: "1" -> "2"
+    config.OLD: "x" -> <unset>
+function hello: update
+    image: "registry/hello:0.0.1" -> "registry/hello:0.0.2"
+    memory: 128 -> 256
+    trigger hello-trigger: update
+        source: "/hello" -> "/hi"
+function new: create
+    image: "" -> "registry/new:0.0.2"
+    trigger new-trigger: create
+        type: "" -> "http"
+        source: "" -> "/new"
+function gone: delete
+    trigger gone-trigger: delete
+`
+	if out := testPlanDeploy(t, ""); out != expected {
+		t.Errorf("expected plan:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestPlanDeployJSON(t *testing.T) {
+	var plan map[string]interface{}
+	if err := json.Unmarshal([]byte(testPlanDeploy(t, "json")), &plan); err != nil {
+		t.Fatal(err)
+	}
+
+	app := plan["app"].(map[string]interface{})
+	if app["name"] != "app" || app["action"] != planUpdate {
+		t.Errorf("expected the app to be updated, got %v", app)
+	}
+	removed := app["changes"].([]interface{})[1].(map[string]interface{})
+	if !reflect.DeepEqual(removed, map[string]interface{}{"field": "config.OLD", "old": "x", "new": nil}) {
+		t.Errorf("expected --sync to remove config.OLD, got %v", removed)
+	}
+
+	fns := plan["functions"].([]interface{})
+	var actions []string
+	for _, f := range fns {
+		f := f.(map[string]interface{})
+		actions = append(actions, f["name"].(string)+" "+f["action"].(string))
+	}
+	if !reflect.DeepEqual(actions, []string{"hello update", "new create", "gone delete"}) {
+		t.Errorf("unexpected function actions %v", actions)
+	}
+	hello := fns[0].(map[string]interface{})
+	if hello["path"] != filepath.Join("DIR", "hello", "func.yaml") {
+		t.Errorf("expected the path of the func file, got %v", hello["path"])
+	}
+	triggers := hello["triggers"].([]interface{})
+	if len(triggers) != 1 || triggers[0].(map[string]interface{})["action"] != planUpdate {
+		t.Errorf("expected the trigger of hello to be updated, got %v", triggers)
+	}
+}
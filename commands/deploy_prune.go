@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	common "github.com/fnproject/cli/common"
+	models "github.com/fnproject/fn_go/modelsv2"
+	"github.com/urfave/cli"
+)
+
+// pruneCandidates returns the functions in app that have no func file in the tree, and the triggers that
+// are no longer declared by the func file of their function. Triggers of pruned functions are included.
+func (p *deploycmd) pruneCandidates(c *cli.Context, app *models.App, funcs []funcToDeploy) ([]*models.Fn, []*models.Trigger, error) {
+	declared := make(map[string]*common.FuncFileV20180708, len(funcs))
+	for _, f := range funcs {
+		declared[f.ff.Name] = f.ff
+	}
+
+	fns, err := common.ListFnsInApp(c, p.clientV2, app)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var pruneFns []*models.Fn
+	var pruneTriggers []*models.Trigger
+	for _, fn := range fns {
+		triggers, err := common.ListTriggersInFunc(c, p.clientV2, fn)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ff, ok := declared[fn.Name]
+		if !ok {
+			pruneFns = append(pruneFns, fn)
+			pruneTriggers = append(pruneTriggers, triggers...)
+			continue
+		}
+		for _, t := range triggers {
+			if !declaresTrigger(ff, t.Name) {
+				pruneTriggers = append(pruneTriggers, t)
+			}
+		}
+	}
+	return pruneFns, pruneTriggers, nil
+}
+
+func declaresTrigger(ff *common.FuncFileV20180708, name string) bool {
+	for _, t := range ff.Triggers {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneApp deletes the functions and triggers in app that are no longer in the tree, after asking the
+// user to confirm unless --force or --yes is set. It fails if the user doesn't confirm, so that a deploy that
+// couldn't prune doesn't look like it succeeded.
+func (p *deploycmd) pruneApp(c *cli.Context, app *models.App, funcs []funcToDeploy) error {
+	fns, triggers, err := p.pruneCandidates(c, app, funcs)
+	if err != nil {
+		return err
+	}
+	if len(fns) == 0 && len(triggers) == 0 {
+		fmt.Fprintln(p.out, "Nothing to prune from app:", app.Name)
+		return nil
+	}
+
+	for _, fn := range fns {
+		fmt.Fprintf(p.out, "Function %s is not in the app tree\n", fn.Name)
+	}
+	fnNames := make(map[string]string, len(fns))
+	for _, fn := range fns {
+		fnNames[fn.ID] = fn.Name
+	}
+	for _, t := range triggers {
+		if _, ok := fnNames[t.FnID]; !ok {
+			fmt.Fprintf(p.out, "Trigger %s is no longer declared by its function\n", t.Name)
+		}
+	}
+
+	if !p.force && !p.yes && !common.ConfirmMultiResourceDeletion(p.stdin, p.out, nil, fns, triggers) {
+		return errors.New("pruning was not confirmed, use --force or --yes to prune without asking")
+	}
+
+	if err := common.DeleteTriggersTo(p.out, c, p.clientV2, triggers); err != nil {
+		return err
+	}
+	return common.DeleteFunctionsTo(p.out, c, p.clientV2, fns)
+}
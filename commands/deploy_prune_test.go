@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/cli/common"
+	models "github.com/fnproject/fn_go/modelsv2"
+	"github.com/urfave/cli"
+)
+
+// testContext returns a context without flags for the commands under test, which read paging flags from it
+func testContext() *cli.Context {
+	return cli.NewContext(cli.NewApp(), flag.NewFlagSet("test", flag.ContinueOnError), nil)
+}
+
+func testPruneServer(t *testing.T) (*fakeFnServer, *deploycmd, *models.App, []funcToDeploy) {
+	app := &models.App{ID: "app1", Name: "app"}
+	s, client := newFakeFnServer(t, []*models.App{app},
+		[]*models.Fn{
+			{ID: "fn1", AppID: "app1", Name: "kept"},
+			{ID: "fn2", AppID: "app1", Name: "removed"},
+		},
+		[]*models.Trigger{
+			{ID: "t1", AppID: "app1", FnID: "fn1", Name: "kept-trigger"},
+			{ID: "t2", AppID: "app1", FnID: "fn1", Name: "undeclared-trigger"},
+			{ID: "t3", AppID: "app1", FnID: "fn2", Name: "removed-trigger"},
+		})
+	funcs := []funcToDeploy{{path: "kept/func.yaml", ff: &common.FuncFileV20180708{
+		Name:     "kept",
+		Triggers: []common.Trigger{{Name: "kept-trigger"}},
+	}}}
+	return s, &deploycmd{clientV2: client, out: ioutil.Discard}, app, funcs
+}
+
+func TestPruneCandidates(t *testing.T) {
+	_, p, app, funcs := testPruneServer(t)
+
+	fns, triggers, err := p.pruneCandidates(testContext(), app, funcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fns) != 1 || fns[0].Name != "removed" {
+		t.Errorf("expected the function missing from the tree, got %v", fns)
+	}
+	var names []string
+	for _, tr := range triggers {
+		names = append(names, tr.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"undeclared-trigger", "removed-trigger"}) {
+		t.Errorf("expected the undeclared trigger and the trigger of the removed function, got %v", names)
+	}
+}
+
+func TestPruneApp(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		force   bool
+		yes     bool
+		stdin   string
+		pruned  bool
+		failure bool
+	}{
+		{name: "confirmed", stdin: "y\n", pruned: true},
+		{name: "declined", stdin: "n\n", failure: true},
+		{name: "no input", stdin: "", failure: true},
+		{name: "force", force: true, pruned: true},
+		{name: "yes", yes: true, pruned: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, p, app, funcs := testPruneServer(t)
+			p.force = tc.force
+			p.yes = tc.yes
+			p.stdin = strings.NewReader(tc.stdin)
+
+			err := p.pruneApp(testContext(), app, funcs)
+			if (err != nil) != tc.failure {
+				t.Fatalf("expected failure %v, got %v", tc.failure, err)
+			}
+			var expected []string
+			if tc.pruned {
+				expected = []string{"delete trigger undeclared-trigger", "delete trigger removed-trigger", "delete fn removed"}
+			}
+			if !reflect.DeepEqual(s.changes, expected) {
+				t.Fatalf("expected changes %v, got %v", expected, s.changes)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	common "github.com/fnproject/cli/common"
+	function "github.com/fnproject/cli/objects/fn"
+	models "github.com/fnproject/fn_go/modelsv2"
+	"github.com/urfave/cli"
+)
+
+// Statuses of a function in a deploy report
+const (
+	reportCreated     = "created"
+	reportUpdated     = "updated"
+	reportSkipped     = "skipped"
+	reportFailed      = "failed"
+	reportNotDeployed = "not_deployed"
+)
+
+// triggerReport is a trigger of a deployed function
+type triggerReport struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// fnReport is the outcome of deploying a single function, as printed by --output json
+type fnReport struct {
+	Name           string             `json:"name"`
+	Path           string             `json:"path"`
+	Status         string             `json:"status"`
+	Version        string             `json:"version,omitempty"`
+	Image          string             `json:"image,omitempty"`
+	Digest         string             `json:"digest,omitempty"`
+	ID             string             `json:"id,omitempty"`
+	InvokeEndpoint string             `json:"invoke_endpoint,omitempty"`
+	Triggers       []triggerReport    `json:"triggers,omitempty"`
+	Durations      map[string]float64 `json:"durations_seconds,omitempty"`
+	Error          string             `json:"error,omitempty"`
+}
+
+// deployReport is everything a deploy did, as printed by --output json
+type deployReport struct {
+	App       string     `json:"app"`
+	Functions []fnReport `json:"functions"`
+}
+
+func (p *deploycmd) jsonOutput() bool {
+	return strings.ToLower(p.output) == "json"
+}
+
+// printDeployReport prints a JSON record of each deployed function to p.stdout, looking up the IDs and
+// endpoints of the functions that are on the server. Functions that can't be looked up are reported with
+// the error, without their IDs and endpoints.
+func (p *deploycmd) printDeployReport(c *cli.Context, app *models.App, results []*deployResult) error {
+	report := deployReport{App: app.Name, Functions: []fnReport{}}
+	for _, r := range results {
+		fr := fnReport{
+			Name:    r.name,
+			Path:    r.path,
+			Version: r.version,
+			Image:   r.image,
+			Digest:  r.digest,
+		}
+		if len(r.durations) > 0 {
+			fr.Durations = make(map[string]float64, len(r.durations))
+			for phase, d := range r.durations {
+				fr.Durations[phase] = d.Seconds()
+			}
+		}
+
+		switch {
+		case !r.attempted:
+			fr.Status = reportNotDeployed
+		case r.err != nil:
+			fr.Status = reportFailed
+			fr.Error = r.err.Error()
+		case r.skipped:
+			fr.Status = reportSkipped
+		case r.created:
+			fr.Status = reportCreated
+		default:
+			fr.Status = reportUpdated
+		}
+
+		if r.attempted && r.err == nil {
+			if err := p.describeDeployedFn(c, app, &fr); err != nil {
+				fr.Error = fmt.Sprintf("could not look up deployed function: %v", err)
+			}
+		}
+		report.Functions = append(report.Functions, fr)
+	}
+
+	enc := json.NewEncoder(p.stdout)
+	enc.SetIndent("", "    ")
+	return enc.Encode(report)
+}
+
+// describeDeployedFn fills in the ID, endpoints and triggers of a function from the server
+func (p *deploycmd) describeDeployedFn(c *cli.Context, app *models.App, fr *fnReport) error {
+	fn, err := function.GetFnByName(p.clientV2, app.ID, fr.Name)
+	if err != nil {
+		return err
+	}
+	fr.ID = fn.ID
+	fr.InvokeEndpoint, _ = fn.Annotations[FnInvokeEndpointAnnotation].(string)
+	if fr.Digest == "" && strings.Contains(fn.Image, "@") {
+		fr.Digest = fn.Image
+	}
+
+	triggers, err := common.ListTriggersInFunc(c, p.clientV2, fn)
+	if err != nil {
+		return err
+	}
+	for _, t := range triggers {
+		endpoint, _ := t.Annotations["fnproject.io/trigger/httpEndpoint"].(string)
+		fr.Triggers = append(fr.Triggers, triggerReport{Name: t.Name, Type: t.Type, Endpoint: endpoint})
+	}
+	return nil
+}
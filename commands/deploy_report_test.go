@@ -0,0 +1,190 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fnproject/cli/common"
+	"github.com/fnproject/cli/config"
+	models "github.com/fnproject/fn_go/modelsv2"
+	"github.com/spf13/viper"
+)
+
+func TestPrintDeployReport(t *testing.T) {
+	app := &models.App{ID: "app1", Name: "app"}
+	_, client := newFakeFnServer(t, []*models.App{app},
+		[]*models.Fn{{
+			ID:          "fn1",
+			AppID:       "app1",
+			Name:        "hello",
+			Image:       "registry/hello@sha256:0d9e",
+			Annotations: map[string]interface{}{FnInvokeEndpointAnnotation: "http://fn/invoke/fn1"},
+		}},
+		[]*models.Trigger{{
+			ID:          "t1",
+			AppID:       "app1",
+			FnID:        "fn1",
+			Name:        "hello-trigger",
+			Type:        "http",
+			Annotations: map[string]interface{}{"fnproject.io/trigger/httpEndpoint": "http://fn/t/app/hello"},
+		}})
+	var stdout bytes.Buffer
+	p := &deploycmd{clientV2: client, stdout: &stdout}
+
+	results := []*deployResult{
+		{name: "hello", path: "hello/func.yaml", attempted: true, created: true, version: "0.0.2", image: "registry/hello:0.0.2",
+			durations: map[string]time.Duration{"build": 1500 * time.Millisecond}},
+		{name: "skipped", path: "skipped/func.yaml", attempted: true, skipped: true, version: "0.0.1", image: "registry/skipped:0.0.1"},
+		{name: "broken", path: "broken/func.yaml", attempted: true, err: errors.New("build failed")},
+		{name: "later", path: "later/func.yaml"},
+	}
+	if err := p.printDeployReport(testContext(), app, results); err != nil {
+		t.Fatal(err)
+	}
+
+	// skipped is not on the server, which is reported on it without failing the report
+	expected := `{
+    "app": "app",
+    "functions": [
+        {
+            "name": "hello",
+            "path": "hello/func.yaml",
+            "status": "created",
+            "version": "0.0.2",
+            "image": "registry/hello:0.0.2",
+            "digest": "registry/hello@sha256:0d9e",
+            "id": "fn1",
+            "invoke_endpoint": "http://fn/invoke/fn1",
+            "triggers": [
+                {
+                    "name": "hello-trigger",
+                    "type": "http",
+                    "endpoint": "http://fn/t/app/hello"
+                }
+            ],
+            "durations_seconds": {
+                "build": 1.5
+            }
+        },
+        {
+            "name": "skipped",
+            "path": "skipped/func.yaml",
+            "status": "skipped",
+            "version": "0.0.1",
+            "image": "registry/skipped:0.0.1",
+            "error": "could not look up deployed function: function skipped not found"
+        },
+        {
+            "name": "broken",
+            "path": "broken/func.yaml",
+            "status": "failed",
+            "error": "build failed"
+        },
+        {
+            "name": "later",
+            "path": "later/func.yaml",
+            "status": "not_deployed"
+        }
+    ]
+}
+`
+	if stdout.String() != expected {
+		t.Errorf("expected report:\n%s\ngot:\n%s", expected, stdout.String())
+	}
+}
+
+func TestDeployReportPush(t *testing.T) {
+	registry := viper.GetString(config.EnvFnRegistry)
+	viper.Set(config.EnvFnRegistry, "registry.example.com/owner")
+	defer viper.Set(config.EnvFnRegistry, registry)
+
+	dir, err := ioutil.TempDir("", "deploy-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer fakeDocker(t, dir, "registry.example.com/owner/hello@sha256:0d9e")()
+	writeTestFile(t, dir, "hello/func.yaml", "schema_version: 20180708\nname: hello\nversion: 0.0.2\nruntime: docker\n")
+	fpath := filepath.Join(dir, "hello", "func.yaml")
+	ff, err := common.ParseFuncFileV20180708(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stdout only gets the report, what the push prints goes to stderr with the rest of the progress
+	stdout, err := ioutil.TempFile(dir, "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+	osStdout := os.Stdout
+	os.Stdout = stdout
+	defer func() { os.Stdout = osStdout }()
+
+	app := &models.App{ID: "app1", Name: "app"}
+	_, client := newFakeFnServer(t, []*models.App{app}, nil, nil)
+	state, err := common.LoadDeployState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var progress, stderr bytes.Buffer
+	p := &deploycmd{clientV2: client, output: "json", noBump: true, state: state, stdout: os.Stdout, out: &progress}
+
+	r := newDeployResult(funcToDeploy{path: fpath, ff: ff})
+	r.attempted = true
+	r.err = p.pushAndUpdate(testContext(), app, fpath, ff, r, common.BuildOutput{Stdout: p.out, Stderr: &stderr}, false)
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if err := p.printDeployReport(testContext(), app, []*deployResult{r}); err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = osStdout
+
+	b, err := ioutil.ReadFile(stdout.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report deployReport
+	if err := json.Unmarshal(b, &report); err != nil {
+		t.Fatalf("expected stdout to only hold the report, got %q: %v", b, err)
+	}
+	if len(report.Functions) != 1 {
+		t.Fatalf("expected a report of hello, got %s", b)
+	}
+	fr := report.Functions[0]
+	if fr.Status != reportCreated || fr.Digest != "registry.example.com/owner/hello@sha256:0d9e" || fr.Error != "" {
+		t.Errorf("expected hello to be created with the digest of the pushed image, got %+v", fr)
+	}
+	if _, ok := fr.Durations["push"]; !ok {
+		t.Errorf("expected the duration of the push, got %v", fr.Durations)
+	}
+	if !bytes.Contains(progress.Bytes(), []byte("Pushing registry.example.com/owner/hello:0.0.2")) {
+		t.Errorf("expected the push to be printed to the progress, got %q", progress.String())
+	}
+	if stderr.String() != "pushed registry.example.com/owner/hello:0.0.2\n" {
+		t.Errorf("expected what the push printed to stderr to go to the stderr of the build output, got %q", stderr.String())
+	}
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/fnproject/cli/common"
+)
+
+// changedFuncsSince returns the func file paths of the functions in dir with changes between the git ref
+// and the working tree, including untracked files. A changed file belongs to the deepest function
+// directory containing it, and a change under one of the app's shared paths changes every function.
+func changedFuncsSince(dir, ref string, funcs []funcToDeploy, shared []string) (map[string]bool, error) {
+	top, err := common.GitOutput(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	diff, err := common.GitOutput(dir, "diff", "--name-only", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := common.GitOutput(dir, "ls-files", "--others", "--exclude-standard", "--full-name")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, f := range strings.Split(diff+"\n"+untracked, "\n") {
+		if f != "" {
+			files = append(files, filepath.Join(top, f))
+		}
+	}
+
+	// git reports paths with symlinks resolved, so the function and shared paths must be too
+	funcDirs := make([]string, len(funcs))
+	for i, f := range funcs {
+		if funcDirs[i], err = filepath.EvalSymlinks(filepath.Dir(f.path)); err != nil {
+			return nil, err
+		}
+	}
+	sharedDirs := make([]string, 0, len(shared))
+	for _, s := range shared {
+		abs, err := filepath.Abs(filepath.Join(dir, s))
+		if err != nil {
+			return nil, err
+		}
+		if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = resolved
+		}
+		sharedDirs = append(sharedDirs, abs)
+	}
+
+	changed := map[string]bool{}
+	for _, file := range files {
+		for _, s := range sharedDirs {
+			if common.IsWithinDir(file, s) {
+				for _, f := range funcs {
+					changed[f.path] = true
+				}
+				return changed, nil
+			}
+		}
+
+		owner := -1
+		for i, d := range funcDirs {
+			if common.IsWithinDir(file, d) && (owner < 0 || len(d) > len(funcDirs[owner])) {
+				owner = i
+			}
+		}
+		if owner >= 0 {
+			changed[funcs[owner].path] = true
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestChangedFuncsSince(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "deploy-since")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	writeTestFile(t, dir, "a/func.yaml", "name: a")
+	writeTestFile(t, dir, "a/nested/func.yaml", "name: nested")
+	writeTestFile(t, dir, "b/func.yaml", "name: b")
+	writeTestFile(t, dir, "lib/util.go", "package lib")
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "init")
+
+	funcs := []funcToDeploy{
+		{path: filepath.Join(dir, "a", "func.yaml")},
+		{path: filepath.Join(dir, "a", "nested", "func.yaml")},
+		{path: filepath.Join(dir, "b", "func.yaml")},
+	}
+	shared := []string{"lib"}
+
+	// a file in a nested function only changes that function, and untracked files count
+	writeTestFile(t, dir, "a/nested/new.go", "package nested")
+	changed, err := changedFuncsSince(dir, "HEAD", funcs, shared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 1 || !changed[funcs[1].path] {
+		t.Fatalf("expected only the nested function to change, got %v", changed)
+	}
+
+	// a change to a shared path changes every function
+	writeTestFile(t, dir, "lib/util.go", "package lib // changed")
+	changed, err = changedFuncsSince(dir, "HEAD", funcs, shared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != len(funcs) {
+		t.Fatalf("expected every function to change, got %v", changed)
+	}
+
+	if _, err := changedFuncsSince(dir, "no-such-ref", funcs, shared); err == nil {
+		t.Fatal("expected an error for an unknown ref")
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	models "github.com/fnproject/fn_go/modelsv2"
+)
+
+// reservedAnnotationPrefixes are the namespaces of annotations managed by the server, --sync never removes them
+var reservedAnnotationPrefixes = []string{"fnproject.io/", "oracle.com/"}
+
+// removedConfigKeys returns the keys of remote that are missing from local
+func removedConfigKeys(local, remote map[string]string) []string {
+	var removed []string
+	for k := range remote {
+		if _, ok := local[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// removedAnnotationKeys returns the keys of remote that are missing from local, ignoring server managed annotations
+func removedAnnotationKeys(local, remote map[string]interface{}) []string {
+	var removed []string
+	for k := range remote {
+		if _, ok := local[k]; ok || isReservedAnnotation(k) {
+			continue
+		}
+		removed = append(removed, k)
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+func isReservedAnnotation(key string) bool {
+	for _, prefix := range reservedAnnotationPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// syncConfig returns a copy of local with the removed keys set to "", which the server treats as a delete
+func syncConfig(local map[string]string, removed []string) map[string]string {
+	res := make(map[string]string, len(local)+len(removed))
+	for k, v := range local {
+		res[k] = v
+	}
+	for _, k := range removed {
+		res[k] = ""
+	}
+	return res
+}
+
+// syncAnnotations returns a copy of local with the removed keys set to "", which the server treats as a delete
+func syncAnnotations(local map[string]interface{}, removed []string) map[string]interface{} {
+	res := make(map[string]interface{}, len(local)+len(removed))
+	for k, v := range local {
+		res[k] = v
+	}
+	for _, k := range removed {
+		res[k] = ""
+	}
+	return res
+}
+
+// syncApp makes desired remove the config, annotations and syslog URL of existing that are not in app.yaml,
+// printing what is removed to out
+func syncApp(out io.Writer, desired, existing *models.App) {
+	removedConfig := removedConfigKeys(desired.Config, existing.Config)
+	removedAnnotations := removedAnnotationKeys(desired.Annotations, existing.Annotations)
+	if len(removedConfig) > 0 {
+		desired.Config = syncConfig(desired.Config, removedConfig)
+	}
+	if len(removedAnnotations) > 0 {
+		desired.Annotations = syncAnnotations(desired.Annotations, removedAnnotations)
+	}
+	var removedSyslog bool
+	if desired.SyslogURL == nil && existing.SyslogURL != nil && *existing.SyslogURL != "" {
+		empty := ""
+		desired.SyslogURL = &empty
+		removedSyslog = true
+	}
+	printSyncRemovals(out, "app", desired.Name, removedConfig, removedAnnotations)
+	if removedSyslog {
+		fmt.Fprintf(out, "Removing syslog_url from app %s\n", desired.Name)
+	}
+}
+
+// syncFn makes desired remove the config and annotations of existing that are not in the func file, printing
+// what is removed to out
+func syncFn(out io.Writer, desired, existing *models.Fn) {
+	removedConfig := removedConfigKeys(desired.Config, existing.Config)
+	removedAnnotations := removedAnnotationKeys(desired.Annotations, existing.Annotations)
+	if len(removedConfig) > 0 {
+		desired.Config = syncConfig(desired.Config, removedConfig)
+	}
+	if len(removedAnnotations) > 0 {
+		desired.Annotations = syncAnnotations(desired.Annotations, removedAnnotations)
+	}
+	printSyncRemovals(out, "function", existing.Name, removedConfig, removedAnnotations)
+}
+
+func printSyncRemovals(out io.Writer, kind, name string, config, annotations []string) {
+	if len(config) > 0 {
+		fmt.Fprintf(out, "Removing config keys from %s %s: %s\n", kind, name, strings.Join(config, ", "))
+	}
+	if len(annotations) > 0 {
+		fmt.Fprintf(out, "Removing annotations from %s %s: %s\n", kind, name, strings.Join(annotations, ", "))
+	}
+}
+
+// syncChanges returns the removals --sync would add to a deploy plan
+func syncChanges(existingConfig, desiredConfig map[string]string, existingAnnotations, desiredAnnotations map[string]interface{}) []fieldChange {
+	var changes []fieldChange
+	for _, k := range removedConfigKeys(desiredConfig, existingConfig) {
+		changes = append(changes, fieldChange{Field: "config." + k, Old: existingConfig[k]})
+	}
+	for _, k := range removedAnnotationKeys(desiredAnnotations, existingAnnotations) {
+		changes = append(changes, fieldChange{Field: "annotations." + k, Old: existingAnnotations[k]})
+	}
+	return changes
+}
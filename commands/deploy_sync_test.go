@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/fnproject/cli/common"
+	function "github.com/fnproject/cli/objects/fn"
+	models "github.com/fnproject/fn_go/modelsv2"
+)
+
+func TestSyncFn(t *testing.T) {
+	existing := &models.Fn{
+		ID:     "fn1",
+		AppID:  "app1",
+		Name:   "hello",
+		Image:  "registry/hello:0.0.1",
+		Config: map[string]string{"KEPT": "1", "REMOVED": "2"},
+		Annotations: map[string]interface{}{
+			"kept":                         "1",
+			"removed":                      "2",
+			FnInvokeEndpointAnnotation:     "http://fn/invoke/fn1",
+			"oracle.com/oci/compartmentId": "ocid1.compartment",
+			common.ImageTagAnnotation:      "registry/hello:0.0.1",
+		},
+	}
+	_, client := newFakeFnServer(t, []*models.App{{ID: "app1", Name: "app"}}, []*models.Fn{existing}, nil)
+
+	desired := &models.Fn{
+		Image:       "registry/hello:0.0.2",
+		Config:      map[string]string{"KEPT": "1"},
+		Annotations: map[string]interface{}{"kept": "1"},
+	}
+	var out bytes.Buffer
+	syncFn(&out, desired, existing)
+
+	// removed keys are set to "", annotations in the namespaces of the server are left alone
+	if !reflect.DeepEqual(desired.Config, map[string]string{"KEPT": "1", "REMOVED": ""}) {
+		t.Errorf("expected REMOVED to be set to \"\", got %v", desired.Config)
+	}
+	if !reflect.DeepEqual(desired.Annotations, map[string]interface{}{"kept": "1", "removed": ""}) {
+		t.Errorf("expected only the removed annotation to be set to \"\", got %v", desired.Annotations)
+	}
+	expected := "Removing config keys from function hello: REMOVED\nRemoving annotations from function hello: removed\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+
+	// which the server takes as removing them
+	if err := function.PutFn(client, existing.ID, desired); err != nil {
+		t.Fatal(err)
+	}
+	fn, err := function.GetFnByName(client, "app1", "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fn.Config, map[string]string{"KEPT": "1"}) {
+		t.Errorf("expected REMOVED to be removed, got %v", fn.Config)
+	}
+	if _, ok := fn.Annotations["removed"]; ok {
+		t.Errorf("expected the removed annotation to be removed, got %v", fn.Annotations)
+	}
+	if _, ok := fn.Annotations[FnInvokeEndpointAnnotation]; !ok {
+		t.Errorf("expected the annotations of the server to be kept, got %v", fn.Annotations)
+	}
+}
+
+func TestSyncApp(t *testing.T) {
+	syslog := "tcp://logs:514"
+	existing := &models.App{
+		Name:        "app",
+		Config:      map[string]string{"REMOVED": "1"},
+		Annotations: map[string]interface{}{"oracle.com/oci/subnetIds": []string{"ocid1.subnet"}},
+		SyslogURL:   &syslog,
+	}
+	desired := &models.App{Name: "app"}
+	var out bytes.Buffer
+	syncApp(&out, desired, existing)
+
+	if !reflect.DeepEqual(desired.Config, map[string]string{"REMOVED": ""}) {
+		t.Errorf("expected REMOVED to be set to \"\", got %v", desired.Config)
+	}
+	if desired.Annotations != nil {
+		t.Errorf("expected the annotations of the server to be left alone, got %v", desired.Annotations)
+	}
+	if desired.SyslogURL == nil || *desired.SyslogURL != "" {
+		t.Errorf("expected the syslog URL to be set to \"\", got %v", desired.SyslogURL)
+	}
+	expected := "Removing config keys from app app: REMOVED\nRemoving syslog_url from app app\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestSyncChanges(t *testing.T) {
+	changes := syncChanges(
+		map[string]string{"KEPT": "1", "REMOVED": "2"}, map[string]string{"KEPT": "1"},
+		map[string]interface{}{"removed": "1", "fnproject.io/fn/invokeEndpoint": "http://fn"}, nil)
+	expected := []fieldChange{
+		{Field: "config.REMOVED", Old: "2"},
+		{Field: "annotations.removed", Old: "1"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fnproject/cli/common"
+)
+
+func testDeploys(n int) ([]funcToDeploy, []*deployResult) {
+	funcs := make([]funcToDeploy, n)
+	results := make([]*deployResult, n)
+	for i := range funcs {
+		funcs[i] = funcToDeploy{path: fmt.Sprintf("fn%d/func.yaml", i), ff: &common.FuncFileV20180708{Name: fmt.Sprintf("fn%d", i)}}
+		results[i] = newDeployResult(funcs[i])
+	}
+	return funcs, results
+}
+
+func TestRunDeploysParallel(t *testing.T) {
+	funcs, results := testDeploys(8)
+
+	var mu sync.Mutex
+	running, most := 0, 0
+	runDeploys(funcs, results, 3, func(f funcToDeploy, r *deployResult) error {
+		mu.Lock()
+		running++
+		if running > most {
+			most = running
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+
+	if most != 3 {
+		t.Errorf("expected 3 deploys at a time, got %d", most)
+	}
+	for _, r := range results {
+		if !r.attempted || r.err != nil {
+			t.Errorf("expected %s to be deployed, got %+v", r.name, r)
+		}
+	}
+}
+
+func TestRunDeploysStopsAfterFailure(t *testing.T) {
+	funcs, results := testDeploys(4)
+
+	runDeploys(funcs, results, 1, func(f funcToDeploy, r *deployResult) error {
+		if f.ff.Name == "fn1" {
+			return errors.New("build failed")
+		}
+		return nil
+	})
+
+	for i, expected := range []bool{true, true, false, false} {
+		if results[i].attempted != expected {
+			t.Errorf("expected %s attempted %v, got %v", results[i].name, expected, results[i].attempted)
+		}
+	}
+	if results[1].err == nil {
+		t.Errorf("expected the failure of fn1 to be recorded")
+	}
+}
+
+func TestRunDeploysFinishesInFlight(t *testing.T) {
+	funcs, results := testDeploys(4)
+
+	// fn0 fails while fn1 is deploying, which is left to finish, and nothing is started after it
+	fn1Started := make(chan struct{})
+	runDeploys(funcs, results, 2, func(f funcToDeploy, r *deployResult) error {
+		switch f.ff.Name {
+		case "fn0":
+			<-fn1Started
+			return errors.New("build failed")
+		case "fn1":
+			close(fn1Started)
+			time.Sleep(50 * time.Millisecond)
+		}
+		return nil
+	})
+
+	for i, expected := range []bool{true, true, false, false} {
+		if results[i].attempted != expected {
+			t.Errorf("expected %s attempted %v, got %v", results[i].name, expected, results[i].attempted)
+		}
+	}
+	if results[1].err != nil {
+		t.Errorf("expected fn1 to finish deploying, got %v", results[1].err)
+	}
+}
+
+func TestLinePrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := newLinePrefixWriter(&out, "hello: ")
+	fmt.Fprint(w, "Pushing image...")
+	fmt.Fprint(w, "done\nUpdating func")
+	if out.String() != "hello: Pushing image...done\n" {
+		t.Errorf("expected only whole lines to be written, got %q", out.String())
+	}
+	w.Flush()
+	if out.String() != "hello: Pushing image...done\nhello: Updating func\n" {
+		t.Errorf("expected the rest to be flushed as a line, got %q", out.String())
+	}
+}
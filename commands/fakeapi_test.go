@@ -0,0 +1,214 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fnproject/fn_go/clientv2"
+	models "github.com/fnproject/fn_go/modelsv2"
+	"github.com/go-openapi/strfmt"
+)
+
+// fakeFnServer is an in memory Fn API for tests of commands that read and change apps, functions and
+// triggers. Updates are merged into the resource, removing the config and annotations set to "", and each
+// change is recorded.
+type fakeFnServer struct {
+	mu       sync.Mutex
+	apps     []*models.App
+	fns      []*models.Fn
+	triggers []*models.Trigger
+	// changes are the changes made to the server in order, eg: "delete fn hello"
+	changes []string
+	nextID  int
+}
+
+// newFakeFnServer starts a fakeFnServer with the given resources and returns a client of it
+func newFakeFnServer(t *testing.T, apps []*models.App, fns []*models.Fn, triggers []*models.Trigger) (*fakeFnServer, *clientv2.Fn) {
+	s := &fakeFnServer{apps: apps, fns: fns, triggers: triggers}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, clientv2.NewHTTPClientWithConfig(strfmt.Default, &clientv2.TransportConfig{
+		Host:     u.Host,
+		BasePath: "/v2",
+		Schemes:  []string{"http"},
+	})
+}
+
+func (s *fakeFnServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v2/"), "/")
+	q := r.URL.Query()
+	var res interface{}
+	switch {
+	case parts[0] == "apps" && r.Method == http.MethodGet && len(parts) == 1:
+		items := []*models.App{}
+		for _, a := range s.apps {
+			if q.Get("name") == "" || a.Name == q.Get("name") {
+				items = append(items, a)
+			}
+		}
+		res = &models.AppList{Items: items}
+	case parts[0] == "fns" && r.Method == http.MethodGet && len(parts) == 1:
+		items := []*models.Fn{}
+		for _, f := range s.fns {
+			if f.AppID == q.Get("app_id") && (q.Get("name") == "" || f.Name == q.Get("name")) {
+				items = append(items, f)
+			}
+		}
+		res = &models.FnList{Items: items}
+	case parts[0] == "triggers" && r.Method == http.MethodGet && len(parts) == 1:
+		items := []*models.Trigger{}
+		for _, t := range s.triggers {
+			if t.AppID == q.Get("app_id") && (q.Get("fn_id") == "" || t.FnID == q.Get("fn_id")) &&
+				(q.Get("name") == "" || t.Name == q.Get("name")) {
+				items = append(items, t)
+			}
+		}
+		res = &models.TriggerList{Items: items}
+	case parts[0] == "apps" && (r.Method == http.MethodPost || r.Method == http.MethodPut):
+		a := &models.App{}
+		for i, existing := range s.apps {
+			if len(parts) == 2 && existing.ID == parts[1] {
+				copyResource(existing, a)
+				s.apps = append(s.apps[:i], s.apps[i+1:]...)
+				break
+			}
+		}
+		if !s.decode(w, r, a) {
+			return
+		}
+		s.record(r, &a.ID, "app", a.Name)
+		a.Config, a.Annotations = withoutEmptyConfig(a.Config), withoutEmptyAnnotations(a.Annotations)
+		s.apps = append(s.apps, a)
+		res = a
+	case parts[0] == "fns" && (r.Method == http.MethodPost || r.Method == http.MethodPut):
+		f := &models.Fn{}
+		for i, existing := range s.fns {
+			if len(parts) == 2 && existing.ID == parts[1] {
+				copyResource(existing, f)
+				s.fns = append(s.fns[:i], s.fns[i+1:]...)
+				break
+			}
+		}
+		if !s.decode(w, r, f) {
+			return
+		}
+		s.record(r, &f.ID, "fn", f.Name)
+		f.Config, f.Annotations = withoutEmptyConfig(f.Config), withoutEmptyAnnotations(f.Annotations)
+		s.fns = append(s.fns, f)
+		res = f
+	case parts[0] == "triggers" && (r.Method == http.MethodPost || r.Method == http.MethodPut):
+		t := &models.Trigger{}
+		for i, existing := range s.triggers {
+			if len(parts) == 2 && existing.ID == parts[1] {
+				copyResource(existing, t)
+				s.triggers = append(s.triggers[:i], s.triggers[i+1:]...)
+				break
+			}
+		}
+		if !s.decode(w, r, t) {
+			return
+		}
+		s.record(r, &t.ID, "trigger", t.Name)
+		s.triggers = append(s.triggers, t)
+		res = t
+	case parts[0] == "fns" && r.Method == http.MethodDelete && len(parts) == 2:
+		for i, f := range s.fns {
+			if f.ID == parts[1] {
+				s.fns = append(s.fns[:i], s.fns[i+1:]...)
+				s.changes = append(s.changes, "delete fn "+f.Name)
+				break
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case parts[0] == "triggers" && r.Method == http.MethodDelete && len(parts) == 2:
+		for i, t := range s.triggers {
+			if t.ID == parts[1] {
+				s.triggers = append(s.triggers[:i], s.triggers[i+1:]...)
+				s.changes = append(s.changes, "delete trigger "+t.Name)
+				break
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		http.Error(w, `{"message": "not implemented by the fake server"}`, http.StatusNotImplemented)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func (s *fakeFnServer) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, fmt.Sprintf(`{"message": %q}`, err.Error()), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// record records a create, giving the resource a new ID, or an update
+func (s *fakeFnServer) record(r *http.Request, id *string, kind, name string) {
+	if r.Method == http.MethodPost {
+		s.nextID++
+		*id = fmt.Sprintf("%s%d", kind, s.nextID)
+		s.changes = append(s.changes, "create "+kind+" "+name)
+		return
+	}
+	s.changes = append(s.changes, "update "+kind+" "+name)
+}
+
+// copyResource deep copies from into to, so that an update is merged into a copy of the resource
+func copyResource(from, to interface{}) {
+	b, _ := json.Marshal(from)
+	json.Unmarshal(b, to)
+}
+
+// withoutEmptyConfig removes the keys set to "", as the server does on updates
+func withoutEmptyConfig(m map[string]string) map[string]string {
+	for k, v := range m {
+		if v == "" {
+			delete(m, k)
+		}
+	}
+	return m
+}
+
+func withoutEmptyAnnotations(m map[string]interface{}) map[string]interface{} {
+	for k, v := range m {
+		if v == "" {
+			delete(m, k)
+		}
+	}
+	return m
+}
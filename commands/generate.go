@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/fnproject/cli/common"
+	"github.com/urfave/cli"
+)
+
+// GenerateCommand returns generate cli.command
+func GenerateCommand() cli.Command {
+	return cli.Command{
+		Name:         "generate",
+		Usage:        "\tGenerate files for a function",
+		Category:     "DEVELOPMENT COMMANDS",
+		Description:  "This command generates files ('dockerfile') for a function.",
+		Hidden:       false,
+		ArgsUsage:    "<subcommand>",
+		Subcommands:  GetCommands(GenerateCmds),
+		BashComplete: common.DefaultBashComplete,
+	}
+}
+
+// GenerateDockerfileCommand returns generate dockerfile cli.command
+func GenerateDockerfileCommand() cli.Command {
+	cmd := generateDockerfileCmd{}
+	return cli.Command{
+		Name:        "dockerfile",
+		Usage:       "Write the Dockerfile a function is built with into its directory",
+		Description: "This command writes the Dockerfile the language runtime of a function builds it with, and a .dockerignore, into the function directory. Builds use the Dockerfile from then on, so it can be reviewed and customised.",
+		ArgsUsage:   "[function-subdirectory]",
+		Flags:       cmd.flags(),
+		Action:      cmd.generate,
+	}
+}
+
+type generateDockerfileCmd struct {
+	cacheMounts   bool
+	dockerRuntime bool
+}
+
+func (g *generateDockerfileCmd) flags() []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:        "cache-mounts",
+			Usage:       "Mount the runtime's dependency caches on RUN steps, the Dockerfile then needs BuildKit to build",
+			Destination: &g.cacheMounts,
+		},
+		cli.BoolFlag{
+			Name:        "docker-runtime",
+			Usage:       "Switch the func file to the docker runtime",
+			Destination: &g.dockerRuntime,
+		},
+		cli.StringFlag{
+			Name:  "working-dir, w",
+			Usage: "Specify the working directory of the function, must be the full path.",
+		},
+	}
+}
+
+func (g *generateDockerfileCmd) generate(c *cli.Context) error {
+	dir := common.GetDir(c)
+	if path := c.Args().First(); path != "" {
+		dir = filepath.Join(dir, path)
+	}
+
+	fpath, ff, err := common.FindAndParseFuncFileV20180708(dir)
+	if err != nil {
+		if _, ok := err.(*common.NotFoundError); ok {
+			return errors.New("no function file found")
+		}
+		return err
+	}
+	if err := common.GenerateDockerfileV20180708(fpath, ff, g.cacheMounts, g.dockerRuntime); err != nil {
+		return err
+	}
+
+	fmt.Printf("Dockerfile generated in %s\n", filepath.Dir(fpath))
+	if g.dockerRuntime {
+		fmt.Printf("%s switched to the docker runtime\n", filepath.Base(fpath))
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeTestFile writes content to name, a path relative to dir, creating the directories it is in
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTestFiles writes files, keyed by their path relative to dir
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		writeTestFile(t, dir, name, content)
+	}
+}
+
+// fakeDocker puts a docker on the PATH that builds and pushes nothing and inspects every image as having digest.
+// The arguments of each call are logged, one call a line, to the docker.log in dir.
+func fakeDocker(t *testing.T, dir, digest string) func() {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake docker is a shell script")
+	}
+	writeTestFile(t, dir, "bin/docker", `#!/bin/sh
+echo "$@" >> "`+filepath.Join(dir, "docker.log")+`"
+case "$1" in
+version) echo 24.0.0 ;;
+push) echo "The push refers to repository [$2]"; echo "pushed $2" >&2 ;;
+image) echo '["`+digest+`"]' ;;
+esac
+`)
+	if err := os.Chmod(filepath.Join(dir, "bin", "docker"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", filepath.Join(dir, "bin")+string(os.PathListSeparator)+path)
+	return func() { os.Setenv("PATH", path) }
+}
+
+// fakeDockerCalls returns the calls of the fake docker in dir, each the arguments it was called with
+func fakeDockerCalls(t *testing.T, dir string) []string {
+	b, err := ioutil.ReadFile(filepath.Join(dir, "docker.log"))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(b)), "\n")
+}
@@ -18,12 +18,16 @@ package commands
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
+	"unicode/utf8"
 
 	"errors"
 
@@ -63,7 +67,37 @@ var InvokeFnFlags = []cli.Flag{
 	},
 	cli.StringFlag{
 		Name:  "output",
-		Usage: "Output format (json)",
+		Usage: "Output format (json), bodies that aren't valid UTF-8 are base64 encoded",
+	},
+	cli.BoolFlag{
+		Name:  "raw",
+		Usage: "Output the HTTP response as received: status line, headers and body. The body isn't decompressed, though a chunked body is written in chunks of its own",
+	},
+	cli.StringFlag{
+		Name:  "output-file",
+		Usage: "Write the response body to this file rather than stdout",
+	},
+	cli.StringSliceFlag{
+		Name:  "header, H",
+		Usage: "A header to send, eg: -H 'Accept: application/json', repeatable",
+	},
+	cli.StringFlag{
+		Name:  "method",
+		Usage: "The HTTP method of the invocation",
+		Value: http.MethodPost,
+	},
+	cli.StringSliceFlag{
+		Name:  "query",
+		Usage: "A query parameter to send, eg: --query page=2, repeatable",
+	},
+	cli.StringSliceFlag{
+		Name:  "env, e",
+		Usage: "An environment variable to send as a header of the same name, NAME to send its value if it is set or NAME=value, repeatable",
+	},
+	cli.StringFlag{
+		Name:  "max-body",
+		Usage: "The largest payload to send, eg: 50MB. Larger payloads fail rather than being cut off",
+		Value: "10MB",
 	},
 }
 
@@ -127,12 +161,29 @@ func (cl *invokeCmd) Invoke(c *cli.Context) error {
 			return fmt.Errorf("Fn invoke url annotation not present, %s", FnInvokeEndpointAnnotation)
 		}
 	}
+	outputFormat := strings.ToLower(c.String("output"))
+	outputFile := c.String("output-file")
+	if c.Bool("raw") && (outputFormat != "" || outputFile != "") {
+		return errors.New("--raw can't be used with --output or --output-file")
+	}
+	maxBodySize, err := client.ParseBytes(c.String("max-body"))
+	if err != nil {
+		return fmt.Errorf("--max-body: %v", err)
+	}
+	header, err := parseHeaders(c.StringSlice("header"))
+	if err != nil {
+		return err
+	}
+	query, err := parseQuery(c.StringSlice("query"))
+	if err != nil {
+		return err
+	}
 	content := stdin()
 	wd := common.GetWd()
 
 	if c.String("content-type") != "" {
 		contentType = c.String("content-type")
-	} else {
+	} else if header.Get("Content-Type") == "" {
 		_, ff, err := common.FindAndParseFuncFileV20180708(wd)
 		if err == nil && ff.Content_type != "" {
 			contentType = ff.Content_type
@@ -142,9 +193,15 @@ func (cl *invokeCmd) Invoke(c *cli.Context) error {
 	resp, err := client.Invoke(cl.provider,
 		client.InvokeRequest{
 			URL:         invokeURL,
+			Method:      c.String("method"),
+			Header:      header,
+			Query:       query,
 			Content:     content,
-			Env:         c.StringSlice("e"),
+			Env:         c.StringSlice("env"),
 			ContentType: contentType,
+			MaxBodySize: maxBodySize,
+			// --raw shows the response as it was sent
+			DisableCompression: c.Bool("raw"),
 		},
 	)
 	if err != nil {
@@ -152,35 +209,112 @@ func (cl *invokeCmd) Invoke(c *cli.Context) error {
 	}
 	defer resp.Body.Close()
 
-	outputFormat := strings.ToLower(c.String("output"))
+	if c.Bool("raw") {
+		return resp.Write(os.Stdout)
+	}
+	if outputFile != "" {
+		return outputToFile(os.Stdout, resp, outputFile, outputFormat == "json", c.Bool("display-call-id"))
+	}
 	if outputFormat == "json" {
-		outputJSON(os.Stdout, resp)
-	} else {
-		outputNormal(os.Stdout, resp, c.Bool("display-call-id"))
+		return outputJSON(os.Stdout, resp)
 	}
-	// TODO we should have a 'raw' option to output the raw http request, it may be useful, idk
-
+	outputNormal(os.Stdout, resp, c.Bool("display-call-id"))
 	return nil
 }
 
-func outputJSON(output io.Writer, resp *http.Response) {
-	var b bytes.Buffer
-	// TODO this is lame
-	io.Copy(&b, resp.Body)
+// parseHeaders parses headers given as "Name: value"
+func parseHeaders(headers []string) (http.Header, error) {
+	h := http.Header{}
+	for _, s := range headers {
+		kv := strings.SplitN(s, ":", 2)
+		name := strings.TrimSpace(kv[0])
+		if len(kv) != 2 || name == "" {
+			return nil, fmt.Errorf("invalid header %q, must be Name: value", s)
+		}
+		h.Add(name, strings.TrimSpace(kv[1]))
+	}
+	return h, nil
+}
+
+// parseQuery parses query parameters given as key=value
+func parseQuery(params []string) (url.Values, error) {
+	q := url.Values{}
+	for _, s := range params {
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid query parameter %q, must be key=value", s)
+		}
+		q.Add(kv[0], kv[1])
+	}
+	return q, nil
+}
+
+// BodyEncodingBase64 is the body_encoding of JSON output whose body isn't valid UTF-8
+const BodyEncodingBase64 = "base64"
+
+// jsonResponse is the JSON output of a response. Bodies that aren't valid UTF-8 text, eg: images, are base64
+// encoded, with BodyEncoding set to say so.
+type jsonResponse struct {
+	Body         string      `json:"body"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+	Headers      http.Header `json:"headers"`
+	StatusCode   int         `json:"status_code"`
+}
 
-	i := struct {
-		Body       string      `json:"body"`
-		Headers    http.Header `json:"headers"`
-		StatusCode int         `json:"status_code"`
-	}{
-		Body:       b.String(),
-		Headers:    resp.Header,
-		StatusCode: resp.StatusCode,
+// jsonFileResponse is the JSON output of a response whose body was written to BodyFile
+type jsonFileResponse struct {
+	BodyFile   string      `json:"body_file"`
+	Headers    http.Header `json:"headers"`
+	StatusCode int         `json:"status_code"`
+}
+
+func outputJSON(output io.Writer, resp *http.Response) error {
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading the response body: %v", err)
 	}
+	i := jsonResponse{Headers: resp.Header, StatusCode: resp.StatusCode}
+	if utf8.Valid(b) {
+		i.Body = string(b)
+	} else {
+		i.Body = base64.StdEncoding.EncodeToString(b)
+		i.BodyEncoding = BodyEncodingBase64
+	}
+	return writeJSON(output, i)
+}
 
+func writeJSON(output io.Writer, v interface{}) error {
 	enc := json.NewEncoder(output)
 	enc.SetIndent("", "    ")
-	enc.Encode(i)
+	return enc.Encode(v)
+}
+
+// outputToFile writes the body of resp to path as is. The status and headers are written to output as JSON
+// if asJSON, with path in place of the body, and otherwise only the call ID is, if includeCallID.
+func outputToFile(output io.Writer, resp *http.Response, path string, asJSON, includeCallID bool) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, resp.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("error writing the response body to %s: %v", path, err)
+	}
+
+	if asJSON {
+		return writeJSON(output, jsonFileResponse{BodyFile: path, Headers: resp.Header, StatusCode: resp.StatusCode})
+	}
+	if cid, ok := resp.Header[CallIDHeader]; ok && includeCallID {
+		fmt.Fprintf(output, "Call ID: %v\n", cid[0])
+	}
+	if resp.StatusCode >= 400 {
+		fmt.Fprintf(os.Stderr, "Warning: the function responded with status %v, its response was written to %s\n", resp.StatusCode, path)
+	}
+	return nil
 }
 
 func outputNormal(output io.Writer, resp *http.Response, includeCallID bool) {
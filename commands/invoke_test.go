@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseHeadersAndQuery(t *testing.T) {
+	h, err := parseHeaders([]string{"Accept: application/json", "X-Trace:a", "X-Trace: b:c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Get("Accept") != "application/json" || len(h["X-Trace"]) != 2 || h["X-Trace"][1] != "b:c" {
+		t.Fatalf("unexpected headers %v", h)
+	}
+	if _, err := parseHeaders([]string{"Accept"}); err == nil {
+		t.Fatal("expected an error for a header without a value")
+	}
+
+	q, err := parseQuery([]string{"page=2", "filter=a=b", "empty="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Get("page") != "2" || q.Get("filter") != "a=b" || q.Get("empty") != "" {
+		t.Fatalf("unexpected query %v", q)
+	}
+	if _, err := parseQuery([]string{"page"}); err == nil {
+		t.Fatal("expected an error for a parameter without =")
+	}
+}
+
+func TestOutputJSON(t *testing.T) {
+	for _, tc := range []struct {
+		body, expected, encoding string
+	}{
+		{body: "héllo", expected: "héllo"},
+		{body: "\x89PNG\r\n\x1a\n\xff", expected: "iVBORw0KGgr/", encoding: BodyEncodingBase64},
+	} {
+		resp := &http.Response{StatusCode: 200, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(tc.body))}
+		var out bytes.Buffer
+		if err := outputJSON(&out, resp); err != nil {
+			t.Fatal(err)
+		}
+		var r jsonResponse
+		if err := json.Unmarshal(out.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+		if r.Body != tc.expected || r.BodyEncoding != tc.encoding {
+			t.Errorf("expected body %q with encoding %q, got %q with %q", tc.expected, tc.encoding, r.Body, r.BodyEncoding)
+		}
+	}
+}
+
+func TestOutputToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "invoke")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "out.bin")
+	body := "\x00\xffbinary"
+
+	resp := &http.Response{StatusCode: 200, Header: http.Header{CallIDHeader: {"01ABC"}}, Body: ioutil.NopCloser(strings.NewReader(body))}
+	var out bytes.Buffer
+	if err := outputToFile(&out, resp, path, false, true); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != body {
+		t.Errorf("expected the body to be written as is, got %q", b)
+	}
+	if out.String() != "Call ID: 01ABC\n" {
+		t.Errorf("expected only the call ID on the output, got %q", out.String())
+	}
+
+	// the JSON output names the file rather than having an empty body
+	resp = &http.Response{StatusCode: 200, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(body))}
+	out.Reset()
+	if err := outputToFile(&out, resp, path, true, false); err != nil {
+		t.Fatal(err)
+	}
+	var r map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r["body"]; ok || r["body_file"] != path {
+		t.Errorf("expected the body file %s and no body, got %s", path, out.String())
+	}
+}
@@ -19,6 +19,7 @@ package commands
 import (
 	"errors"
 	"fmt"
+	"os"
 
 	"github.com/fnproject/cli/common"
 	"github.com/urfave/cli"
@@ -41,7 +42,8 @@ func PushCommand() cli.Command {
 }
 
 type pushcmd struct {
-	registry string
+	registry    string
+	fromArchive string
 }
 
 func (p *pushcmd) flags() []cli.Flag {
@@ -56,6 +58,11 @@ func (p *pushcmd) flags() []cli.Flag {
 			Usage:       "Set the Docker owner for images and optionally the registry. This will be prefixed to your function name for pushing to Docker registries.\n eg: `--registry username` will set your Docker Hub owner. `--registry registry.hub.docker.com/username` will set the registry and owner.",
 			Destination: &p.registry,
 		},
+		cli.StringFlag{
+			Name:        "from-archive",
+			Usage:       "Push the image in an archive written by fn build --export instead of a locally built image",
+			Destination: &p.fromArchive,
+		},
 	}
 }
 
@@ -75,6 +82,20 @@ func (p *pushcmd) push(c *cli.Context) error {
 			return err
 		}
 
+		if p.fromArchive != "" {
+			a, err := common.ReadImageArchive(p.fromArchive)
+			if err != nil {
+				return err
+			}
+			if a.Name != ff.Name {
+				return fmt.Errorf("archive %s holds function %s, not %s", p.fromArchive, a.Name, ff.Name)
+			}
+			ff.Version = a.Version
+			if err := common.LoadImageArchive(os.Stdout, p.fromArchive, ff.ImageNameV20180708()); err != nil {
+				return err
+			}
+		}
+
 		fmt.Println("pushing", ff.ImageNameV20180708())
 
 		if err := common.DockerPushV20180708(ff); err != nil {
@@ -85,6 +106,9 @@ func (p *pushcmd) push(c *cli.Context) error {
 		return nil
 	}
 
+	if p.fromArchive != "" {
+		return errors.New("--from-archive requires a func file with schema_version 20180708 or later")
+	}
 	_, ff, err := common.LoadFuncfile(".")
 
 	if err != nil {
@@ -0,0 +1,36 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"github.com/fnproject/cli/common"
+	"github.com/urfave/cli"
+)
+
+// RollbackCommand returns rollback cli.command
+func RollbackCommand() cli.Command {
+	return cli.Command{
+		Name:         "rollback",
+		Usage:        "\tRoll an object back to an earlier revision",
+		Category:     "MANAGEMENT COMMANDS",
+		Description:  "This command rolls an object ('function') back to a revision recorded by an earlier deploy.",
+		Hidden:       false,
+		ArgsUsage:    "<subcommand>",
+		Subcommands:  GetCommands(RollbackCmds),
+		BashComplete: common.DefaultBashComplete,
+	}
+}
@@ -20,7 +20,6 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"syscall"
@@ -99,10 +98,14 @@ func start(c *cli.Context) error {
 	image := fmt.Sprintf("%s:%s", common.FunctionsDockerImage, c.String("version"))
 
 	args = append(args, image)
-	cmd := exec.Command("docker", args...)
+	engine, err := common.Engine()
+	if err != nil {
+		return err
+	}
+	cmd := engine.Command(args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	err := cmd.Start()
+	err = cmd.Start()
 	if err != nil {
 		log.Fatalln("Starting command failed:", err)
 	}
@@ -19,8 +19,8 @@ package commands
 import (
 	"errors"
 	"fmt"
-	"os/exec"
 
+	"github.com/fnproject/cli/common"
 	"github.com/urfave/cli"
 )
 
@@ -35,8 +35,12 @@ func StopCommand() cli.Command {
 	}
 }
 func stop(c *cli.Context) error {
-	cmd := exec.Command("docker", "stop", "fnserver")
-	err := cmd.Run()
+	engine, err := common.Engine()
+	if err != nil {
+		return err
+	}
+	cmd := engine.Command("stop", "fnserver")
+	err = cmd.Run()
 	if err != nil {
 		return errors.New("Failed to stop 'fnserver'")
 	}
@@ -43,6 +43,12 @@ type AppFile struct {
 	Config      map[string]string      `yaml:"config,omitempty" json:"config,omitempty"`
 	Annotations map[string]interface{} `yaml:"annotations,omitempty" json:"annotations,omitempty"`
 	SyslogURL   string                 `yaml:"syslog_url,omitempty" json:"syslog_url,omitempty"`
+	// SharedPaths are directories, relative to the app, used by every function. deploy --since
+	// deploys every function when they change.
+	SharedPaths []string `yaml:"shared_paths,omitempty" json:"shared_paths,omitempty"`
+	// Build_context is the directory, relative to the app, that the images of its functions are built from
+	// unless they set their own. Generated builds copy the shared paths under it into the image.
+	Build_context string `yaml:"build_context,omitempty" json:"build_context,omitempty"`
 }
 
 func findAppfile(path string) (string, error) {
@@ -55,13 +61,34 @@ func findAppfile(path string) (string, error) {
 	return "", NewNotFoundError("Could not find app file")
 }
 
-// LoadAppfile returns a parsed appfile.
+// findAppfileAbove returns the path of the app file in dir or the nearest of its parents that has one
+func findAppfileAbove(dir string) (string, error) {
+	for {
+		if fn, err := findAppfile(dir); err == nil {
+			return fn, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", NewNotFoundError("Could not find app file")
+		}
+		dir = parent
+	}
+}
+
+// LoadAppfile returns a parsed appfile, with the overlay for the current context merged over it.
 func LoadAppfile(path string) (*AppFile, error) {
 	fn, err := findAppfile(path)
 	if err != nil {
 		return nil, err
 	}
-	return parseAppfile(fn)
+	af, err := parseAppfile(fn)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyOverlay(fn, af); err != nil {
+		return nil, err
+	}
+	return af, nil
 }
 
 func parseAppfile(path string) (*AppFile, error) {
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Image archive formats
+const (
+	ArchiveFormatDocker = "docker"
+	ArchiveFormatOCI    = "oci"
+)
+
+// ImageArchive describes the function image exported to an archive by fn build --export, so that it
+// can be pushed or deployed somewhere the function wasn't built.
+type ImageArchive struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Image   string `json:"image"`
+	Format  string `json:"format"`
+}
+
+// ImageArchiveMetadataPath returns the path of the metadata file that goes with the archive at path
+func ImageArchiveMetadataPath(path string) string {
+	return path + ".json"
+}
+
+// ExportImage saves the image of the function to an archive at path in format, along with its metadata file
+func ExportImage(ff *FuncFileV20180708, path, format string) error {
+	if format != ArchiveFormatDocker && format != ArchiveFormatOCI {
+		return fmt.Errorf("unknown archive format %s, must be %s or %s", format, ArchiveFormatDocker, ArchiveFormatOCI)
+	}
+	engine, err := Engine()
+	if err != nil {
+		return err
+	}
+	a := &ImageArchive{
+		Name:    ff.Name,
+		Version: ff.Version,
+		Image:   ff.ImageNameV20180708(),
+		Format:  format,
+	}
+	if err := engine.Save(a.Image, path, format == ArchiveFormatOCI); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(ImageArchiveMetadataPath(path), b, 0644)
+}
+
+// ReadImageArchive reads the metadata of the archive at path
+func ReadImageArchive(path string) (*ImageArchive, error) {
+	mpath := ImageArchiveMetadataPath(path)
+	b, err := ioutil.ReadFile(mpath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s for parsing. Error: %v", mpath, err)
+	}
+	a := &ImageArchive{}
+	if err := json.Unmarshal(b, a); err != nil {
+		return nil, fmt.Errorf("could not parse %s. Error: %v", mpath, err)
+	}
+	if a.Image == "" {
+		return nil, fmt.Errorf("%s has no image", mpath)
+	}
+	return a, nil
+}
+
+// LoadImageArchive loads the archive at path, tagging its image as image, and prints what it loads to out
+func LoadImageArchive(out io.Writer, path, image string) error {
+	engine, err := Engine()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Loading %v from %v...\n", image, path)
+	return engine.Load(path, image)
+}
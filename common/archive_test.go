@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadImageArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "image-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "func.tar")
+	if _, err := ReadImageArchive(path); err == nil {
+		t.Fatal("expected an error for an archive without metadata")
+	}
+
+	metadata := `{"name": "hello", "version": "0.0.2", "image": "owner/hello:0.0.2", "format": "docker"}`
+	if err := ioutil.WriteFile(ImageArchiveMetadataPath(path), []byte(metadata), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a, err := ReadImageArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ImageArchive{Name: "hello", Version: "0.0.2", Image: "owner/hello:0.0.2", Format: ArchiveFormatDocker}
+	if *a != expected {
+		t.Fatalf("expected %+v, got %+v", expected, *a)
+	}
+}
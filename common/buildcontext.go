@@ -0,0 +1,371 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FnIgnoreFile lists, in .dockerignore syntax, files of a function directory that aren't sent to its builds,
+// on top of the ones its language helper and the .dockerignore of its build context ignore
+const FnIgnoreFile = ".fnignore"
+
+// LargeBuildContextSize is the size of a build context above which builds warn that it is large
+const LargeBuildContextSize = 100 * 1024 * 1024
+
+// BuildContext is the directory the image of a function is built from
+type BuildContext struct {
+	// Dir is the root of the build context
+	Dir string
+	// FuncDir is the function directory, Dir or a directory under it
+	FuncDir string
+	// SharedPaths are the shared paths of the app of the function that are under Dir, outside of FuncDir
+	SharedPaths []string
+}
+
+// BuildContextV20180708 returns the build context of the function at fpath: the build_context of its func
+// file, or else of the app file in its directory or the nearest parent with one, or else its directory
+func BuildContextV20180708(fpath string, ff *FuncFileV20180708) (*BuildContext, error) {
+	funcDir, err := filepath.Abs(filepath.Dir(fpath))
+	if err != nil {
+		return nil, err
+	}
+	bc := &BuildContext{Dir: funcDir, FuncDir: funcDir}
+
+	var af *AppFile
+	appDir := ""
+	if appfile, err := findAppfileAbove(funcDir); err == nil {
+		appDir = filepath.Dir(appfile)
+		if af, err = LoadAppfile(appDir); err != nil {
+			return nil, err
+		}
+	}
+	if ff.Build_context != "" {
+		bc.Dir = filepath.Join(funcDir, ff.Build_context)
+	} else if af != nil && af.Build_context != "" {
+		bc.Dir = filepath.Join(appDir, af.Build_context)
+	}
+	if !IsWithinDir(funcDir, bc.Dir) {
+		return nil, fmt.Errorf("the build context %s must contain the function directory %s", bc.Dir, funcDir)
+	}
+	if bc.Dir == funcDir || af == nil {
+		return bc, nil
+	}
+	for _, p := range af.SharedPaths {
+		shared := filepath.Join(appDir, p)
+		if IsWithinDir(shared, bc.Dir) && !IsWithinDir(shared, funcDir) && !IsWithinDir(funcDir, shared) {
+			bc.SharedPaths = append(bc.SharedPaths, shared)
+		}
+	}
+	return bc, nil
+}
+
+// rel returns path relative to the root of the build context, separated by /
+func (bc *BuildContext) rel(p string) string {
+	rel, err := filepath.Rel(bc.Dir, p)
+	if err != nil {
+		return p
+	}
+	return filepath.ToSlash(rel)
+}
+
+// dockerfileLines rewrites the sources of the ADD and COPY instructions of the Dockerfile lines a language
+// helper generates for the function directory to the build context. Where the whole function directory is
+// copied the shared paths are copied too, to the same place relative to it, so relative imports still work.
+func (bc *BuildContext) dockerfileLines(lines []string) []string {
+	funcRel := bc.rel(bc.FuncDir)
+	if funcRel == "." {
+		return lines
+	}
+	var r []string
+	workdir := "/"
+	for _, l := range lines {
+		fields := strings.Fields(l)
+		if len(fields) < 2 {
+			r = append(r, l)
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "FROM":
+			workdir = "/"
+		case "WORKDIR":
+			workdir = path.Join(workdir, fields[1])
+		}
+		instr := strings.ToUpper(fields[0])
+		if (instr != "ADD" && instr != "COPY") || strings.HasPrefix(fields[1], "[") || strings.Contains(l, "--from=") {
+			r = append(r, l)
+			continue
+		}
+		args := fields[1:]
+		var flags []string
+		for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+			flags, args = append(flags, args[0]), args[1:]
+		}
+		if len(args) < 2 {
+			r = append(r, l)
+			continue
+		}
+		srcs, dest := args[:len(args)-1], args[len(args)-1]
+		wholeDir := false
+		for i, src := range srcs {
+			if path.Clean(src) == "." {
+				wholeDir = true
+				srcs[i] = funcRel + "/"
+			} else {
+				srcs[i] = path.Join(funcRel, src)
+				if strings.HasSuffix(src, "/") {
+					srcs[i] += "/"
+				}
+			}
+		}
+		r = append(r, strings.Join(append(append(append([]string{fields[0]}, flags...), srcs...), dest), " "))
+		if !wholeDir {
+			continue
+		}
+		if !path.IsAbs(dest) {
+			dest = path.Join(workdir, dest)
+		}
+		for _, shared := range bc.SharedPaths {
+			fromFunc, err := filepath.Rel(bc.FuncDir, shared)
+			if err != nil {
+				continue
+			}
+			r = append(r, fmt.Sprintf("COPY %s/ %s/", bc.rel(shared), path.Join(dest, filepath.ToSlash(fromFunc))))
+		}
+	}
+	return r
+}
+
+// stageBuildContext copies the files of the build context that patterns, in .dockerignore syntax, don't
+// ignore into a temporary directory to build from, so that nothing is written to the build context itself.
+// It warns on w if what is copied is unusually large. The returned func removes the directory.
+func stageBuildContext(w io.Writer, bc *BuildContext, patterns []string) (string, func(), error) {
+	staged, err := ioutil.TempDir("", "fn-build-context")
+	if err != nil {
+		return "", func() {}, err
+	}
+	cleanup := func() { os.RemoveAll(staged) }
+	size, err := copyBuildContext(bc.Dir, staged, newIgnoreMatcher(patterns))
+	if err != nil {
+		cleanup()
+		return "", func() {}, fmt.Errorf("could not copy the build context %s: %v", bc.Dir, err)
+	}
+	warnLargeBuildContext(w, bc.Dir, size)
+	return staged, cleanup, nil
+}
+
+// checkBuildContextSize warns on w if the files of the build context that patterns don't ignore are unusually large
+func checkBuildContextSize(w io.Writer, bc *BuildContext, patterns []string) error {
+	size, err := buildContextSize(bc.Dir, newIgnoreMatcher(patterns))
+	if err != nil {
+		return err
+	}
+	warnLargeBuildContext(w, bc.Dir, size)
+	return nil
+}
+
+func warnLargeBuildContext(w io.Writer, dir string, size int64) {
+	if size > LargeBuildContextSize {
+		fmt.Fprintf(w, "Warning: the build context of %s is %dMB. Add files the image doesn't need to %s to leave them out of the build\n",
+			dir, size/(1024*1024), FnIgnoreFile)
+	}
+}
+
+// readIgnoreFile returns the patterns of the .dockerignore style file at path, or nil if there isn't one
+func readIgnoreFile(path string) ([]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+	patterns := []string{}
+	for _, l := range strings.Split(string(b), "\n") {
+		if l = strings.TrimSpace(l); l != "" && !strings.HasPrefix(l, "#") {
+			patterns = append(patterns, l)
+		}
+	}
+	return patterns, nil
+}
+
+// buildContextSize returns the size of the files in dir that m doesn't ignore
+func buildContextSize(dir string, m *ignoreMatcher) (int64, error) {
+	var size int64
+	err := walkBuildContext(dir, m, func(rel string, info os.FileInfo) error {
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// copyBuildContext copies the files in src that m doesn't ignore to dst, hard linking them where it can, and
+// returns their size
+func copyBuildContext(src, dst string, m *ignoreMatcher) (int64, error) {
+	var size int64
+	err := walkBuildContext(src, m, func(rel string, info os.FileInfo) error {
+		target := filepath.Join(dst, rel)
+		// the directory may be ignored, with an exception for what is in it
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(filepath.Join(src, rel))
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			size += info.Size()
+			return linkOrCopyFile(filepath.Join(src, rel), target, info.Mode())
+		}
+		return nil
+	})
+	return size, err
+}
+
+// walkBuildContext calls fn with the path relative to dir of each file and directory in dir that m doesn't ignore
+func walkBuildContext(dir string, m *ignoreMatcher, fn func(rel string, info os.FileInfo) error) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if m.ignored(filepath.ToSlash(rel)) {
+			// files in an ignored directory can only be sent if an exception may match them
+			if info.IsDir() && !m.hasExceptions {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return fn(rel, info)
+	})
+}
+
+func linkOrCopyFile(src, dst string, mode os.FileMode) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+type ignorePattern struct {
+	re        *regexp.Regexp
+	exception bool
+}
+
+// ignoreMatcher matches paths against .dockerignore patterns: the last matching pattern decides whether
+// a path is ignored, and patterns starting with ! are exceptions
+type ignoreMatcher struct {
+	patterns      []ignorePattern
+	hasExceptions bool
+}
+
+func newIgnoreMatcher(lines []string) *ignoreMatcher {
+	m := &ignoreMatcher{}
+	for _, l := range lines {
+		p := ignorePattern{}
+		if strings.HasPrefix(l, "!") {
+			p.exception = true
+			m.hasExceptions = true
+			l = l[1:]
+		}
+		l = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(l)), "/")
+		p.re = regexp.MustCompile("^" + ignorePatternRegexp(l) + "$")
+		m.patterns = append(m.patterns, p)
+	}
+	return m
+}
+
+// ignorePatternRegexp converts a .dockerignore pattern to a regular expression, where ** matches any number
+// of directories and * and ? don't match /
+func ignorePatternRegexp(pattern string) string {
+	var re strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '*' && strings.HasPrefix(pattern[i:], "**/"):
+			re.WriteString("(.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(pattern[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return re.String()
+}
+
+// ignored reports whether path, relative to the build context and separated by /, is ignored. A pattern
+// matching a directory matches everything in it.
+func (m *ignoreMatcher) ignored(path string) bool {
+	ignored := false
+	for _, p := range m.patterns {
+		if p.exception == ignored && p.matches(path) {
+			ignored = !p.exception
+		}
+	}
+	return ignored
+}
+
+func (p ignorePattern) matches(path string) bool {
+	for {
+		if p.re.MatchString(path) {
+			return true
+		}
+		i := strings.LastIndex(path, "/")
+		if i < 0 {
+			return false
+		}
+		path = path[:i]
+	}
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/cli/langs"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	m := newIgnoreMatcher([]string{"target", "/build/*.log", "**/*.pyc", "node_modules", "!node_modules/keep"})
+	for path, expected := range map[string]bool{
+		"target":                 true,
+		"target/classes/A.class": true,
+		"src/target":             false,
+		"build/out.log":          true,
+		"build/sub/out.log":      false,
+		"a.pyc":                  true,
+		"pkg/sub/a.pyc":          true,
+		"node_modules/left-pad":  true,
+		"node_modules/keep/x.js": false,
+		"func.py":                false,
+	} {
+		if m.ignored(path) != expected {
+			t.Errorf("expected %s to be ignored: %v", path, expected)
+		}
+	}
+}
+
+func TestStageBuildContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "build-context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestFile(t, dir, "func.py", "")
+	writeTestFile(t, dir, ".venv/lib.py", "")
+	writeTestFile(t, dir, "secrets.env", "")
+	writeTestFile(t, dir, "debug.log", "")
+	writeTestFile(t, dir, "keep.log", "")
+	writeTestFile(t, dir, ".dockerignore", "*.log\n")
+	// the .fnignore applies on top of the build context's own .dockerignore, and comes last to undo any ignore
+	writeTestFile(t, dir, FnIgnoreFile, "# local files\nsecrets.env\n!keep.log\n")
+	files := func(root string) []string {
+		var r []string
+		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				rel, _ := filepath.Rel(root, path)
+				r = append(r, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+		return r
+	}
+	before := files(dir)
+
+	bc := &BuildContext{Dir: dir, FuncDir: dir}
+	ignores, err := dockerignoreLines(bc, langs.GetLangHelper("python"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var warnings bytes.Buffer
+	staged, cleanup, err := stageBuildContext(&warnings, bc, ignores)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warnings.Len() != 0 {
+		t.Errorf("expected no warning for a small build context, got %q", warnings.String())
+	}
+	if copied := files(staged); !reflect.DeepEqual(copied, []string{"func.py", "keep.log"}) {
+		t.Errorf("expected the files the build uses to be copied, got %v", copied)
+	}
+	cleanup()
+	if Exists(staged) {
+		t.Error("expected the staged build context to be removed")
+	}
+	if after := files(dir); !reflect.DeepEqual(after, before) {
+		t.Errorf("expected the build context to be left alone, had %v and now %v", before, after)
+	}
+}
+
+func TestWarnLargeBuildContext(t *testing.T) {
+	var warnings bytes.Buffer
+	warnLargeBuildContext(&warnings, "fns/hello", LargeBuildContextSize+1)
+	if !strings.HasPrefix(warnings.String(), "Warning: the build context of fns/hello is 100MB.") {
+		t.Errorf("expected a warning about the size of the build context, got %q", warnings.String())
+	}
+}
+
+func TestBuildContextV20180708(t *testing.T) {
+	dir, err := ioutil.TempDir("", "build-context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, "app.yaml", "name: app\nbuild_context: .\nshared_paths: [lib, fns/hello/local]\n")
+	writeTestFile(t, dir, "lib/util.py", "")
+	writeTestFile(t, dir, "fns/hello/func.yaml", "name: hello\nruntime: python\n")
+	writeTestFile(t, dir, "fns/other/func.yaml", "name: other\nruntime: python\n")
+	fpath := filepath.Join(dir, "fns", "hello", "func.yaml")
+
+	bc, err := BuildContextV20180708(fpath, &FuncFileV20180708{Name: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// shared paths in the function directory are already in the build
+	if bc.Dir != dir || len(bc.SharedPaths) != 1 || bc.SharedPaths[0] != filepath.Join(dir, "lib") {
+		t.Fatalf("expected the app's build context and shared lib, got %+v", bc)
+	}
+
+	lines := bc.dockerfileLines([]string{
+		"FROM fnproject/python:3.8-dev as build-stage",
+		"WORKDIR /function",
+		"ADD requirements.txt /function/",
+		"ADD . /function/",
+		"FROM fnproject/python:3.8",
+		"COPY --from=build-stage /python /python",
+	})
+	expected := []string{
+		"FROM fnproject/python:3.8-dev as build-stage",
+		"WORKDIR /function",
+		"ADD fns/hello/requirements.txt /function/",
+		"ADD fns/hello/ /function/",
+		"COPY lib/ /lib/",
+		"FROM fnproject/python:3.8",
+		"COPY --from=build-stage /python /python",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected Dockerfile lines\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+
+	ignored, err := dockerignoreLines(bc, langs.GetLangHelper("python"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !contains(ignored, "fns/other") || contains(ignored, "fns/hello") || !contains(ignored, "fns/hello/.venv") {
+		t.Fatalf("expected other functions and the function's helper ignores, got %v", ignored)
+	}
+
+	// the func file's build context comes before the app's, and must hold the function
+	bc, err = BuildContextV20180708(fpath, &FuncFileV20180708{Name: "hello", Build_context: "."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bc.Dir != bc.FuncDir || len(bc.SharedPaths) != 0 {
+		t.Fatalf("expected the function directory as build context, got %+v", bc)
+	}
+	if _, err := BuildContextV20180708(fpath, &FuncFileV20180708{Name: "hello", Build_context: "sub"}); err == nil {
+		t.Fatal("expected an error for a build context inside the function")
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fnproject/cli/config"
+)
+
+// buildLogTailLines is how much of the build log is printed when a build fails
+const buildLogTailLines = 20
+
+var (
+	// eg: Step 3/9 : RUN ["mvn", "package"]
+	classicBuildStep = regexp.MustCompile(`^Step (\d+/\d+) : (.*)$`)
+	// eg: #7 [build-stage 3/6] RUN ["mvn", "package"]
+	buildKitBuildStep = regexp.MustCompile(`^#\d+ \[(?:\S+ )?(\d+/\d+)\] (.*)$`)
+)
+
+// BuildLogPath returns the file the output of the last build of image is kept in. Logs are kept in the
+// fn config directory rather than the function directory, where they would change the build context.
+func BuildLogPath(image string) string {
+	name := strings.NewReplacer("/", "_", ":", "_").Replace(ImageRepository(image))
+	return filepath.Join(config.GetBuildLogsPath(), name+".log")
+}
+
+func createBuildLog(image string) (*os.File, error) {
+	path := BuildLogPath(image)
+	if err := os.MkdirAll(filepath.Dir(path), config.ReadWritePerms); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+// printBuildLogTail prints the last lines of the build log at path to w
+func printBuildLogTail(w io.Writer, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > buildLogTailLines {
+			lines = lines[1:]
+		}
+	}
+	for _, l := range lines {
+		fmt.Fprintln(w, l)
+	}
+	fmt.Fprintf(w, "Full build log: %s\n", path)
+}
+
+// buildProgress is a writer for build output that shows the step being run on a single line of out
+type buildProgress struct {
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func newBuildProgress(out io.Writer, prefix string) *buildProgress {
+	return &buildProgress{out: out, prefix: prefix}
+}
+
+func (p *buildProgress) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		p.line(strings.TrimSpace(string(p.buf[:i])))
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+func (p *buildProgress) line(l string) {
+	m := classicBuildStep.FindStringSubmatch(l)
+	if m == nil {
+		m = buildKitBuildStep.FindStringSubmatch(l)
+	}
+	if m == nil {
+		return
+	}
+	step := strings.Join(strings.Fields(m[2]), " ")
+	if len(step) > 60 {
+		step = step[:57] + "..."
+	}
+	// \r\033[K returns to the start of the line and clears it
+	fmt.Fprintf(p.out, "\r\033[K%s[%s] %s", p.prefix, m[1], step)
+}
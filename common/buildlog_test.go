@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBuildProgress(t *testing.T) {
+	var out bytes.Buffer
+	p := newBuildProgress(&out, "Building image fn ")
+
+	// lines may be split across writes
+	for _, w := range []string{
+		"Sending build context to Docker daemon  4.096kB\nStep 1/2 : FROM fnproject/go:dev",
+		"\n ---> 1b2c\n",
+		"#7 [build-stage 3/6] RUN [\"mvn\", \"package\"]\n",
+		"#1 [internal] load build definition from Dockerfile\n",
+	} {
+		if _, err := p.Write([]byte(w)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := "\r\033[KBuilding image fn [1/2] FROM fnproject/go:dev" +
+		"\r\033[KBuilding image fn [3/6] RUN [\"mvn\", \"package\"]"
+	if out.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, out.String())
+	}
+}
@@ -18,6 +18,8 @@ package common
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/coreos/go-semver/semver"
@@ -192,8 +194,13 @@ func bumpItWdV20180708(wd string, vtype VType) (*FuncFileV20180708, error) {
 
 // BumpIt returns updated funcfile
 func BumpItV20180708(fpath string, vtype VType) (*FuncFileV20180708, error) {
+	return BumpItV20180708To(os.Stdout, fpath, vtype)
+}
+
+// BumpItV20180708To bumps the version of the func file at fpath as BumpItV20180708 does, printing the new version to out
+func BumpItV20180708To(out io.Writer, fpath string, vtype VType) (*FuncFileV20180708, error) {
 	// fmt.Println("Bumping version in func file at: ", fpath)
-	funcfile, err := ParseFuncFileV20180708(fpath)
+	funcfile, err := parseFuncFileV20180708(fpath)
 	if err != nil {
 		return nil, err
 	}
@@ -206,7 +213,7 @@ func BumpItV20180708(fpath string, vtype VType) (*FuncFileV20180708, error) {
 	if err := storeFuncFileV20180708(fpath, funcfile); err != nil {
 		return nil, err
 	}
-	fmt.Println("Bumped to version", funcfile.Version)
+	fmt.Fprintln(out, "Bumped to version", funcfile.Version)
 	return funcfile, nil
 }
 
@@ -238,3 +245,13 @@ func bumpVersionV20180708(funcfile *FuncFileV20180708, t VType) (*FuncFileV20180
 	funcfile.Version = newver.String()
 	return funcfile, nil
 }
+
+// NextVersionV20180708 returns the version a bump would give funcfile, without changing funcfile or its file
+func NextVersionV20180708(funcfile *FuncFileV20180708, vtype VType) (string, error) {
+	next := *funcfile
+	bumped, err := bumpVersionV20180708(&next, vtype)
+	if err != nil {
+		return "", err
+	}
+	return bumped.Version, nil
+}
@@ -31,11 +31,10 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
-	"unicode"
 
-	"github.com/coreos/go-semver/semver"
 	"github.com/fatih/color"
 	"github.com/fnproject/cli/config"
 	"github.com/fnproject/cli/langs"
@@ -43,6 +42,7 @@ import (
 	apifns "github.com/fnproject/fn_go/clientv2/fns"
 	apitriggers "github.com/fnproject/fn_go/clientv2/triggers"
 	"github.com/fnproject/fn_go/modelsv2"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/viper"
 	"github.com/urfave/cli"
 	"gopkg.in/yaml.v2"
@@ -110,7 +110,7 @@ func BuildFunc(verbose bool, fpath string, funcfile *FuncFile, buildArg []string
 	if err != nil {
 		return nil, err
 	}
-	if err := localBuild(fpath, funcfile.Build); err != nil {
+	if err := localBuild(StdBuildOutput(), verbose, fpath, buildSteps(funcfile.Build)); err != nil {
 		return nil, err
 	}
 
@@ -121,33 +121,77 @@ func BuildFunc(verbose bool, fpath string, funcfile *FuncFile, buildArg []string
 	return funcfile, nil
 }
 
-// BuildFunc bumps version and builds function.
-func BuildFuncV20180708(verbose bool, fpath string, funcfile *FuncFileV20180708, buildArg []string, noCache bool) (*FuncFileV20180708, error) {
+// BuildOutput is where a build prints to. Stdout gets its progress messages and, in verbose mode, the output
+// of build steps and of the engine. Stderr gets the progress of the image build and what went wrong.
+type BuildOutput struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	// Concurrent is set when other builds print to the same output, so that the build only prints whole
+	// lines, without the progress line that is otherwise redrawn or added to as the image builds.
+	Concurrent bool
+}
+
+// StdBuildOutput is the output of builds that print to the standard output and error of the process
+func StdBuildOutput() BuildOutput {
+	return BuildOutput{Stdout: os.Stdout, Stderr: os.Stderr}
+}
+
+// BuildFunc bumps version and builds function. secrets are BuildKit secrets, eg: id=npmrc,src=.npmrc. push is only
+// used when the func file has platforms, as images built by buildx are pushed as they are built.
+func BuildFuncV20180708(out BuildOutput, verbose bool, fpath string, funcfile *FuncFileV20180708, buildArg, secrets []string, noCache, push bool) (*FuncFileV20180708, error) {
 	var err error
 
-	if funcfile.Version == "" {
-		funcfile, err = BumpItV20180708(fpath, Patch)
-		if err != nil {
+	// kept as the func file may be read again, without flags that override it
+	sbom := funcfile.SBOM
+	if sbom != "" {
+		if err := CheckSBOMFormat(sbom); err != nil {
 			return nil, err
 		}
 	}
 
+	if err := versionFuncFileV20180708(out.Stdout, fpath, funcfile); err != nil {
+		return nil, err
+	}
+
 	funcfile, err = imageStampFuncFileV20180708(fpath, funcfile)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := localBuild(fpath, funcfile.Build); err != nil {
+	if err := localBuild(out, verbose, fpath, funcfile.Build); err != nil {
 		return nil, err
 	}
 
-	if err := dockerBuildV20180708(verbose, fpath, funcfile, buildArg, noCache); err != nil {
+	if err := dockerBuildV20180708(out, verbose, fpath, funcfile, buildArg, secrets, noCache, push); err != nil {
 		return nil, err
 	}
 
+	if sbom != "" {
+		funcfile.SBOM = sbom
+		dir := filepath.Dir(fpath)
+		if err := WriteSBOMV20180708(dir, funcfile, sbom, SBOMPath(dir, sbom)); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(out.Stdout, "Software bill of materials written to %v\n", SBOMPath(dir, sbom))
+	}
+
 	return funcfile, nil
 }
 
+// versionFuncFileV20180708 gives the func file at fpath its initial version if funcfile, which has any overlay
+// merged into it, has no version. Only the version of funcfile is changed, the rest of it is kept.
+func versionFuncFileV20180708(out io.Writer, fpath string, funcfile *FuncFileV20180708) error {
+	if funcfile.Version != "" {
+		return nil
+	}
+	bumped, err := BumpItV20180708To(out, fpath, Patch)
+	if err != nil {
+		return err
+	}
+	funcfile.Version = bumped.Version
+	return nil
+}
+
 func imageStampFuncFile(fpath string, funcfile *FuncFile) (*FuncFile, error) {
 
 	dir := filepath.Dir(fpath)
@@ -202,7 +246,7 @@ func imageStampFuncFile(fpath string, funcfile *FuncFile) (*FuncFile, error) {
 func imageStampFuncFileV20180708(fpath string, funcfile *FuncFileV20180708) (*FuncFileV20180708, error) {
 
 	dir := filepath.Dir(fpath)
-	dockerfile := filepath.Join(dir, "Dockerfile")
+	dockerfile := funcfile.DockerfilePath(dir)
 
 	// detect if build and run image both are absent and runtime is not docker then update them
 	if !Exists(dockerfile) && funcfile.Runtime != FuncfileDockerRuntime && funcfile.Build_image == "" && funcfile.Run_image == "" {
@@ -237,8 +281,14 @@ func imageStampFuncFileV20180708(fpath string, funcfile *FuncFileV20180708) (*Fu
 			funcfile.Run_image = ri
 		}
 
-		// fill back yaml file
-		err = EncodeFuncFileV20180708YAML(fpath, funcfile)
+		// fill back yaml file, without the overlay merged into funcfile
+		base, err := parseFuncFileV20180708(fpath)
+		if err != nil {
+			return funcfile, err
+		}
+		base.Build_image = funcfile.Build_image
+		base.Run_image = funcfile.Run_image
+		err = EncodeFuncFileV20180708YAML(fpath, base)
 		if err != nil {
 			return funcfile, err
 		}
@@ -247,35 +297,112 @@ func imageStampFuncFileV20180708(fpath string, funcfile *FuncFileV20180708) (*Fu
 	return funcfile, nil
 }
 
-func localBuild(path string, steps []string) error {
-	for _, cmd := range steps {
-		exe := exec.Command("/bin/sh", "-c", cmd)
-		exe.Dir = filepath.Dir(path)
-		if err := exe.Run(); err != nil {
-			return fmt.Errorf("error running command %v (%v)", cmd, err)
+// localBuild runs the build steps of the func file at path. Their output is streamed in verbose mode,
+// and otherwise kept to report what a failed step printed.
+func localBuild(out BuildOutput, verbose bool, path string, steps []BuildStep) error {
+	for i, step := range steps {
+		if err := runBuildStep(out, verbose, filepath.Dir(path), step); err != nil {
+			return fmt.Errorf("build step %d of %d failed: %v", i+1, len(steps), err)
 		}
 	}
 
 	return nil
 }
 
+func runBuildStep(out BuildOutput, verbose bool, dir string, step BuildStep) error {
+	ctx := context.Background()
+	if step.Timeout != "" {
+		timeout, err := time.ParseDuration(step.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout %q of command %v: %v", step.Timeout, step.Run, err)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	exe := exec.Command("/bin/sh", "-c", step.Run)
+	startInProcessGroup(exe)
+	exe.Dir = dir
+	if step.Dir != "" {
+		exe.Dir = filepath.Join(dir, step.Dir)
+	}
+	if len(step.Env) > 0 {
+		exe.Env = os.Environ()
+		for k, v := range step.Env {
+			exe.Env = append(exe.Env, k+"="+v)
+		}
+	}
+
+	var printed bytes.Buffer
+	if verbose {
+		fmt.Fprintf(out.Stderr, "Running build command: %v\n", step.Run)
+		exe.Stdout = out.Stdout
+		exe.Stderr = out.Stderr
+	} else {
+		exe.Stdout = &printed
+		exe.Stderr = &printed
+	}
+
+	err := exe.Start()
+	if err == nil {
+		done := make(chan error, 1)
+		go func() { done <- exe.Wait() }()
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			// killing only the shell would leave the commands it started running, and holding its output open
+			killProcessGroup(exe)
+			<-done
+			err = fmt.Errorf("timed out after %v", step.Timeout)
+		}
+	}
+	if err != nil {
+		msg := fmt.Sprintf("error running command %v (%v)", step.Run, err)
+		if printed := strings.TrimSpace(printed.String()); printed != "" {
+			msg += ", it printed:\n" + tailLines(printed, buildLogTailLines)
+		}
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// tailLines returns the last n lines of s
+func tailLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// PrintContextualInfo prints the registry and context in use to the standard output
 func PrintContextualInfo() {
+	FprintContextualInfo(os.Stdout)
+}
+
+// FprintContextualInfo prints the registry and context in use to w
+func FprintContextualInfo(w io.Writer) {
 	var registry, currentContext string
 	registry = viper.GetString(config.EnvFnRegistry)
 	if registry == "" {
 		registry = "FN_REGISTRY is not set."
 	}
-	fmt.Println("FN_REGISTRY: ", registry)
+	fmt.Fprintln(w, "FN_REGISTRY: ", registry)
 
 	currentContext = viper.GetString(config.CurrentContext)
 	if currentContext == "" {
 		currentContext = "No context currently in use."
 	}
-	fmt.Println("Current Context: ", currentContext)
+	fmt.Fprintln(w, "Current Context: ", currentContext)
 }
 
 func dockerBuild(verbose bool, fpath string, ff *FuncFile, buildArgs []string, noCache bool) error {
-	err := dockerVersionCheck()
+	engine, err := Engine()
+	if err != nil {
+		return err
+	}
+	err = engine.VersionCheck()
 	if err != nil {
 		return err
 	}
@@ -299,19 +426,19 @@ func dockerBuild(verbose bool, fpath string, ff *FuncFile, buildArgs []string, n
 		}
 		defer os.Remove(dockerfile)
 		if helper.HasPreBuild() {
-			err := helper.PreBuild()
+			err := helper.PreBuild(dir)
 			if err != nil {
 				return err
 			}
 		}
 	}
-	err = RunBuild(verbose, dir, ff.ImageName(), dockerfile, buildArgs, noCache)
+	err = RunBuild(StdBuildOutput(), verbose, dir, ff.ImageName(), dockerfile, buildArgs, BuildOptions{NoCache: noCache})
 	if err != nil {
 		return err
 	}
 
 	if helper != nil {
-		err := helper.AfterBuild()
+		err := helper.AfterBuild(dir)
 		if err != nil {
 			return err
 		}
@@ -319,17 +446,31 @@ func dockerBuild(verbose bool, fpath string, ff *FuncFile, buildArgs []string, n
 	return nil
 }
 
-func dockerBuildV20180708(verbose bool, fpath string, ff *FuncFileV20180708, buildArgs []string, noCache bool) error {
-	err := dockerVersionCheck()
+func dockerBuildV20180708(out BuildOutput, verbose bool, fpath string, ff *FuncFileV20180708, buildArgs, secrets []string, noCache, push bool) error {
+	engine, err := Engine()
+	if err != nil {
+		return err
+	}
+	err = engine.VersionCheck()
 	if err != nil {
 		return err
 	}
 
 	dir := filepath.Dir(fpath)
+	bc, err := BuildContextV20180708(fpath, ff)
+	if err != nil {
+		return err
+	}
 
 	var helper langs.LangHelper
-	dockerfile := filepath.Join(dir, "Dockerfile")
+	dockerfile, err := filepath.Abs(ff.DockerfilePath(dir))
+	if err != nil {
+		return err
+	}
 	if !Exists(dockerfile) {
+		if ff.Dockerfile != "" {
+			return fmt.Errorf("Dockerfile %s does not exist", dockerfile)
+		}
 		if ff.Runtime == FuncfileDockerRuntime {
 			return fmt.Errorf("Dockerfile does not exist for 'docker' runtime")
 		}
@@ -338,25 +479,73 @@ func dockerBuildV20180708(verbose bool, fpath string, ff *FuncFileV20180708, bui
 		if helper == nil {
 			return fmt.Errorf("Cannot build, no language helper found for %v", ff.Runtime)
 		}
-		dockerfile, err = writeTmpDockerfileV20180708(helper, dir, ff)
-		if err != nil {
+		if err := checkHelperPlatforms(helper, ff.Platforms); err != nil {
 			return err
 		}
-		defer os.Remove(dockerfile)
 		if helper.HasPreBuild() {
-			err := helper.PreBuild()
+			err := helper.PreBuild(dir)
 			if err != nil {
 				return err
 			}
 		}
 	}
-	err = RunBuild(verbose, dir, ff.ImageNameV20180708(), dockerfile, buildArgs, noCache)
+
+	// builds from a generated Dockerfile, or with a .fnignore the engine doesn't know about, run from a copy of
+	// the build context without the ignored files. Others leave the engine to apply the build context's .dockerignore.
+	contextDir := bc.Dir
+	if helper != nil || Exists(filepath.Join(bc.FuncDir, FnIgnoreFile)) {
+		var ignores []string
+		if helper != nil {
+			ignores, err = dockerignoreLines(bc, helper)
+		} else {
+			ignores, err = userIgnoreLines(bc)
+		}
+		if err != nil {
+			return err
+		}
+		var cleanup func()
+		contextDir, cleanup, err = stageBuildContext(out.Stderr, bc, ignores)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+	} else {
+		ignores, err := userIgnoreLines(bc)
+		if err != nil {
+			return err
+		}
+		if err := checkBuildContextSize(out.Stderr, bc, ignores); err != nil {
+			return err
+		}
+	}
+
+	if helper != nil {
+		secretIDs, err := buildSecretIDs(secrets)
+		if err != nil {
+			return err
+		}
+		// buildx always builds with BuildKit
+		runMounts := (engine.Buildx && len(ff.Platforms) > 0) || engine.RunMounts()
+		dockerfile, err = writeTmpDockerfileV20180708(contextDir, helper, bc, ff, runMounts, secretIDs)
+		if err != nil {
+			return err
+		}
+	}
+
+	labels := ImageLabelsV20180708(dir, ff)
+	err = RunBuild(out, verbose, contextDir, ff.ImageNameV20180708(), dockerfile, buildArgs, BuildOptions{
+		Secrets:   secrets,
+		Labels:    labels,
+		Platforms: ff.Platforms,
+		NoCache:   noCache,
+		Push:      push,
+	})
 	if err != nil {
 		return err
 	}
 
 	if helper != nil {
-		err := helper.AfterBuild()
+		err := helper.AfterBuild(dir)
 		if err != nil {
 			return err
 		}
@@ -364,8 +553,32 @@ func dockerBuildV20180708(verbose bool, fpath string, ff *FuncFileV20180708, bui
 	return nil
 }
 
-// RunBuild runs function from func.yaml/json/yml.
-func RunBuild(verbose bool, dir, imageName, dockerfile string, buildArgs []string, noCache bool) error {
+// BuildOptions are the options of an image build by RunBuild
+type BuildOptions struct {
+	// Secrets are passed to the build as BuildKit secrets, which unlike build args don't end up in the image
+	Secrets []string
+	// Labels are set on the image
+	Labels map[string]string
+	// Platforms are built for by docker buildx. Engines without buildx can only build for one platform.
+	Platforms []string
+	NoCache   bool
+	// Push pushes the images buildx builds for Platforms as they are built
+	Push bool
+}
+
+// RunBuild runs function from func.yaml/json/yml. What the build prints goes to out.
+func RunBuild(out BuildOutput, verbose bool, dir, imageName, dockerfile string, buildArgs []string, opts BuildOptions) error {
+	engine, err := Engine()
+	if err != nil {
+		return err
+	}
+	if len(opts.Platforms) > 1 && !engine.Buildx {
+		return fmt.Errorf("%s cannot build an image for more than one platform", engine.Name)
+	}
+	if len(opts.Secrets) > 0 && !(engine.Buildx && len(opts.Platforms) > 0) && !engine.RunMounts() {
+		return fmt.Errorf("build secrets need BuildKit, which this version of %s doesn't use. Set DOCKER_BUILDKIT=1 for docker", engine.Name)
+	}
+
 	cancel := make(chan os.Signal, 3)
 	signal.Notify(cancel, os.Interrupt) // and others perhaps
 	defer signal.Stop(cancel)
@@ -376,12 +589,28 @@ func RunBuild(verbose bool, dir, imageName, dockerfile string, buildArgs []strin
 	buildErr := ioutil.Discard
 
 	quit := make(chan struct{})
-	fmt.Fprintf(os.Stderr, "Building image %v ", imageName)
+	prefix := fmt.Sprintf("Building image %v ", imageName)
+	// endLine ends the line the progress of the build is printed on
+	endLine := func() { fmt.Fprintln(out.Stderr) }
+	if out.Concurrent {
+		fmt.Fprintf(out.Stderr, "Building image %v...\n", imageName)
+		endLine = func() {}
+	} else {
+		fmt.Fprint(out.Stderr, prefix)
+	}
 	if verbose {
-		fmt.Println()
-		buildOut = os.Stdout
-		buildErr = os.Stderr
-		PrintContextualInfo()
+		if !out.Concurrent {
+			fmt.Fprintln(out.Stdout)
+		}
+		buildOut = out.Stdout
+		buildErr = out.Stderr
+		FprintContextualInfo(out.Stdout)
+	} else if out.Concurrent {
+		// only the build log gets the output of the build
+	} else if isTerminal(out.Stderr) {
+		progress := newBuildProgress(out.Stderr, prefix)
+		buildOut = progress
+		buildErr = progress
 	} else {
 		// print dots. quit channel explanation: https://stackoverflow.com/a/16466581/105562
 		ticker := time.NewTicker(1 * time.Second)
@@ -389,7 +618,7 @@ func RunBuild(verbose bool, dir, imageName, dockerfile string, buildArgs []strin
 			for {
 				select {
 				case <-ticker.C:
-					fmt.Fprintf(os.Stderr, ".")
+					fmt.Fprintf(out.Stderr, ".")
 				case <-quit:
 					ticker.Stop()
 					return
@@ -398,13 +627,40 @@ func RunBuild(verbose bool, dir, imageName, dockerfile string, buildArgs []strin
 		}()
 	}
 
+	// the output of every build is kept, verbose or not
+	buildLog, err := createBuildLog(imageName)
+	if err != nil {
+		fmt.Fprintf(out.Stderr, "\nCould not create build log: %v\n", err)
+	} else {
+		defer buildLog.Close()
+		if buildOut == buildErr {
+			// a single writer so that the output isn't written concurrently
+			buildOut = io.MultiWriter(buildLog, buildOut)
+			buildErr = buildOut
+		} else {
+			buildOut = io.MultiWriter(buildLog, buildOut)
+			buildErr = io.MultiWriter(buildLog, buildErr)
+		}
+	}
+
 	go func(done chan<- error) {
-		args := []string{
-			"build",
+		args := []string{"build"}
+		if len(opts.Platforms) > 0 && !engine.Buildx {
+			args = append(args, "--platform", opts.Platforms[0])
+		} else if len(opts.Platforms) > 0 {
+			args = []string{"buildx", "build", "--platform", strings.Join(opts.Platforms, ",")}
+			// docker can only load an image for a single platform, a manifest list has to be pushed
+			if opts.Push {
+				args = append(args, "--push")
+			} else if len(opts.Platforms) == 1 {
+				args = append(args, "--load")
+			}
+		}
+		args = append(args,
 			"-t", imageName,
 			"-f", dockerfile,
-		}
-		if noCache {
+		)
+		if opts.NoCache {
 			args = append(args, "--no-cache")
 		}
 
@@ -413,11 +669,22 @@ func RunBuild(verbose bool, dir, imageName, dockerfile string, buildArgs []strin
 				args = append(args, "--build-arg", buildArg)
 			}
 		}
+		for _, secret := range opts.Secrets {
+			args = append(args, "--secret", secret)
+		}
+		var labelKeys []string
+		for k := range opts.Labels {
+			labelKeys = append(labelKeys, k)
+		}
+		sort.Strings(labelKeys)
+		for _, k := range labelKeys {
+			args = append(args, "--label", k+"="+opts.Labels[k])
+		}
 		args = append(args,
 			"--build-arg", "HTTP_PROXY",
 			"--build-arg", "HTTPS_PROXY",
 			".")
-		cmd := exec.Command("docker", args...)
+		cmd := engine.Command(args...)
 		cmd.Dir = dir
 		cmd.Stderr = buildErr // Doesn't look like there's any output to stderr on docker build, whether it's successful or not.
 		cmd.Stdout = buildOut
@@ -427,41 +694,134 @@ func RunBuild(verbose bool, dir, imageName, dockerfile string, buildArgs []strin
 	select {
 	case err := <-result:
 		close(quit)
-		fmt.Fprintln(os.Stderr)
+		endLine()
 		if err != nil {
 			if verbose == false {
-				fmt.Printf("%v Run with `--verbose` flag to see what went wrong. eg: `fn --verbose CMD`\n", color.RedString("Error during build."))
+				if buildLog != nil {
+					fmt.Fprintf(out.Stderr, "%v\n", color.RedString("Error during build."))
+					printBuildLogTail(out.Stderr, buildLog.Name())
+				} else {
+					fmt.Fprintf(out.Stdout, "%v Run with `--verbose` flag to see what went wrong. eg: `fn --verbose CMD`\n", color.RedString("Error during build."))
+				}
 			}
-			return fmt.Errorf("error running docker build: %v", err)
+			return fmt.Errorf("error running %s build: %v", engine.Name, err)
+		}
+		if len(opts.Platforms) > 1 && !opts.Push {
+			fmt.Fprintf(out.Stderr, "Image %v was built for %v but is only kept in the buildx cache, it must be pushed to be used\n",
+				imageName, strings.Join(opts.Platforms, ", "))
 		}
 	case signal := <-cancel:
 		close(quit)
-		fmt.Fprintln(os.Stderr)
+		endLine()
 		return fmt.Errorf("build cancelled on signal %v", signal)
 	}
 	return nil
 }
 
-func dockerVersionCheck() error {
-	out, err := exec.Command("docker", "version", "--format", "{{.Server.Version}}").Output()
-	if err != nil {
-		return fmt.Errorf("Cannot connect to the Docker daemon, make sure you have it installed and running: %v", err)
+// isTerminal reports whether w is a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && isatty.IsTerminal(f.Fd())
+}
+
+// checkHelperPlatforms returns an error if a platform isn't one the images of the language helper are published for
+func checkHelperPlatforms(helper langs.LangHelper, platforms []string) error {
+	supported := helper.Platforms()
+	for _, p := range platforms {
+		found := false
+		for _, s := range supported {
+			if p == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("runtime %v has no images for platform %v, it supports %v. Use a Dockerfile to build for other platforms",
+				helper.Runtime(), p, strings.Join(supported, ", "))
+		}
 	}
-	// dev / test builds append '-ce', trim this
-	trimmed := strings.TrimRightFunc(string(out), func(r rune) bool { return r != '.' && !unicode.IsDigit(r) })
+	return nil
+}
 
-	v, err := semver.NewVersion(trimmed)
-	if err != nil {
-		return fmt.Errorf("could not check Docker version: %v", err)
+// buildSecretIDs returns the ids of BuildKit secrets such as id=npmrc,src=.npmrc
+func buildSecretIDs(secrets []string) ([]string, error) {
+	var ids []string
+	for _, s := range secrets {
+		id := ""
+		for _, kv := range strings.Split(s, ",") {
+			if strings.HasPrefix(kv, "id=") {
+				id = strings.TrimPrefix(kv, "id=")
+			}
+		}
+		if id == "" {
+			return nil, fmt.Errorf("build secret %s has no id, eg: id=npmrc,src=.npmrc", s)
+		}
+		ids = append(ids, id)
 	}
-	vMin, err := semver.NewVersion(MinRequiredDockerVersion)
+	return ids, nil
+}
+
+// ReadBuildArgFile reads the build args in a file with a KEY=VALUE, or just KEY to pass on the variable from
+// the environment, on each line. Blank lines and lines starting with # are skipped.
+func ReadBuildArgFile(path string) ([]string, error) {
+	b, err := ioutil.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("our bad, sorry... please make an issue, detailed error: %v", err)
+		return nil, fmt.Errorf("could not read build arg file %s: %v", path, err)
 	}
-	if v.LessThan(*vMin) {
-		return fmt.Errorf("please upgrade your version of Docker to %s or greater", MinRequiredDockerVersion)
+	var args []string
+	for _, l := range strings.Split(string(b), "\n") {
+		if l = strings.TrimSpace(l); l != "" && !strings.HasPrefix(l, "#") {
+			args = append(args, l)
+		}
 	}
-	return nil
+	return args, nil
+}
+
+// BuildArgs returns the build args of the --build-arg-file files then those of --build-arg, which take
+// precedence as the last value of a build arg is used
+func BuildArgs(c *cli.Context) ([]string, error) {
+	var args []string
+	for _, f := range c.StringSlice("build-arg-file") {
+		fileArgs, err := ReadBuildArgFile(f)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, fileArgs...)
+	}
+	return append(args, c.StringSlice("build-arg")...), nil
+}
+
+// BuildSecrets returns the --secret build secrets with a relative src made absolute, so that like
+// --build-arg-file it is relative to the working directory rather than to the build context
+func BuildSecrets(c *cli.Context) ([]string, error) {
+	var secrets []string
+	for _, s := range c.StringSlice("secret") {
+		kvs := strings.Split(s, ",")
+		for i, kv := range kvs {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) != 2 || (parts[0] != "src" && parts[0] != "source") || filepath.IsAbs(parts[1]) {
+				continue
+			}
+			src, err := filepath.Abs(parts[1])
+			if err != nil {
+				return nil, err
+			}
+			kvs[i] = parts[0] + "=" + src
+		}
+		secrets = append(secrets, strings.Join(kvs, ","))
+	}
+	return secrets, nil
+}
+
+// ParsePlatforms splits a comma separated list of platforms such as linux/amd64,linux/arm64
+func ParsePlatforms(s string) []string {
+	var platforms []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			platforms = append(platforms, p)
+		}
+	}
+	return platforms
 }
 
 // Exists check file exists.
@@ -474,6 +834,15 @@ func Exists(name string) bool {
 	return true
 }
 
+// IsWithinDir reports whether path is dir or under it
+func IsWithinDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 func writeTmpDockerfile(helper langs.LangHelper, dir string, ff *FuncFile) (string, error) {
 	if ff.Entrypoint == "" && ff.Cmd == "" {
 		return "", errors.New("entrypoint and cmd are missing, you must provide one or the other")
@@ -506,7 +875,7 @@ func writeTmpDockerfile(helper langs.LangHelper, dir string, ff *FuncFile) (stri
 		dfLines = append(dfLines, fmt.Sprintf("FROM %s", bi))
 	}
 	dfLines = append(dfLines, "WORKDIR /function")
-	dfLines = append(dfLines, helper.DockerfileBuildCmds()...)
+	dfLines = append(dfLines, helper.DockerfileBuildCmds(dir)...)
 	if helper.IsMultiStage() {
 		// final stage
 		ri := ff.RunImage
@@ -524,7 +893,7 @@ func writeTmpDockerfile(helper langs.LangHelper, dir string, ff *FuncFile) (stri
 		}
 		dfLines = append(dfLines, fmt.Sprintf("FROM %s", ri))
 		dfLines = append(dfLines, "WORKDIR /function")
-		dfLines = append(dfLines, helper.DockerfileCopyCmds()...)
+		dfLines = append(dfLines, helper.DockerfileCopyCmds(dir)...)
 	}
 	if ff.Entrypoint != "" {
 		dfLines = append(dfLines, fmt.Sprintf("ENTRYPOINT [%s]", stringToSlice(ff.Entrypoint)))
@@ -539,10 +908,15 @@ func writeTmpDockerfile(helper langs.LangHelper, dir string, ff *FuncFile) (stri
 	return fd.Name(), err
 }
 
-func writeTmpDockerfileV20180708(helper langs.LangHelper, dir string, ff *FuncFileV20180708) (string, error) {
-	if ff.Entrypoint == "" && ff.Cmd == "" {
-		return "", errors.New("entrypoint and cmd are missing, you must provide one or the other")
+// writeTmpDockerfileV20180708 writes the Dockerfile of a function built by helper from the build context bc
+// to a temporary file in dir. If runMounts is set the helper's cache directories, and the secrets of secretIDs
+// it can use, are mounted on the RUN steps of the build stage.
+func writeTmpDockerfileV20180708(dir string, helper langs.LangHelper, bc *BuildContext, ff *FuncFileV20180708, runMounts bool, secretIDs []string) (string, error) {
+	dfLines, err := dockerfileLinesV20180708(helper, bc.FuncDir, ff, runMounts, secretIDs)
+	if err != nil {
+		return "", err
 	}
+	dfLines = bc.dockerfileLines(dfLines)
 
 	fd, err := ioutil.TempFile(dir, "Dockerfile")
 	if err != nil {
@@ -550,6 +924,19 @@ func writeTmpDockerfileV20180708(helper langs.LangHelper, dir string, ff *FuncFi
 	}
 	defer fd.Close()
 
+	err = writeLines(fd, dfLines)
+	if err != nil {
+		return "", err
+	}
+	return fd.Name(), err
+}
+
+func dockerfileLinesV20180708(helper langs.LangHelper, dir string, ff *FuncFileV20180708, runMounts bool, secretIDs []string) ([]string, error) {
+	if ff.Entrypoint == "" && ff.Cmd == "" {
+		return nil, errors.New("entrypoint and cmd are missing, you must provide one or the other")
+	}
+
+	var err error
 	// multi-stage build: https://medium.com/travis-on-docker/multi-stage-docker-builds-for-creating-tiny-go-images-e0e1867efe5a
 	dfLines := []string{}
 	bi := ff.Build_image
@@ -557,7 +944,7 @@ func writeTmpDockerfileV20180708(helper langs.LangHelper, dir string, ff *FuncFi
 	if bi == "" {
 		bi, err = helper.BuildFromImage()
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 	}
 	if helper.IsMultiStage() {
@@ -567,19 +954,36 @@ func writeTmpDockerfileV20180708(helper langs.LangHelper, dir string, ff *FuncFi
 		dfLines = append(dfLines, fmt.Sprintf("FROM %s", bi))
 	}
 	dfLines = append(dfLines, "WORKDIR /function")
-	dfLines = append(dfLines, helper.DockerfileBuildCmds()...)
+	buildCmds := helper.DockerfileBuildCmds(dir)
+	if runMounts {
+		if cb, ok := helper.(langs.CachedBuilder); ok {
+			buildCmds = cb.DockerfileCachedBuildCmds(dir)
+		}
+		var mounts []string
+		for _, d := range helper.CacheDirs() {
+			mounts = append(mounts, "type=cache,target="+d)
+		}
+		targets := helper.SecretMounts()
+		for _, id := range secretIDs {
+			if target, ok := targets[id]; ok {
+				mounts = append(mounts, fmt.Sprintf("type=secret,id=%s,target=%s", id, target))
+			}
+		}
+		buildCmds = withRunMounts(buildCmds, mounts)
+	}
+	dfLines = append(dfLines, buildCmds...)
 	if helper.IsMultiStage() {
 		// final stage
 		ri := ff.Run_image
 		if ri == "" {
 			ri, err = helper.RunFromImage()
 			if err != nil {
-				return "", err
+				return nil, err
 			}
 		}
 		dfLines = append(dfLines, fmt.Sprintf("FROM %s", ri))
 		dfLines = append(dfLines, "WORKDIR /function")
-		dfLines = append(dfLines, helper.DockerfileCopyCmds()...)
+		dfLines = append(dfLines, helper.DockerfileCopyCmds(dir)...)
 	}
 	if ff.Entrypoint != "" {
 		dfLines = append(dfLines, fmt.Sprintf("ENTRYPOINT [%s]", stringToSlice(ff.Entrypoint)))
@@ -587,11 +991,26 @@ func writeTmpDockerfileV20180708(helper langs.LangHelper, dir string, ff *FuncFi
 	if ff.Cmd != "" {
 		dfLines = append(dfLines, fmt.Sprintf("CMD [%s]", stringToSlice(ff.Cmd)))
 	}
-	err = writeLines(fd, dfLines)
-	if err != nil {
-		return "", err
+	return dfLines, nil
+}
+
+// withRunMounts adds each BuildKit mount, eg: type=cache,target=/root/.npm, to the RUN instructions in lines
+func withRunMounts(lines []string, mounts []string) []string {
+	if len(mounts) == 0 {
+		return lines
 	}
-	return fd.Name(), err
+	var flags bytes.Buffer
+	for _, m := range mounts {
+		fmt.Fprintf(&flags, "--mount=%s ", m)
+	}
+	r := make([]string, len(lines))
+	for i, l := range lines {
+		if strings.HasPrefix(strings.TrimSpace(l), "RUN ") {
+			l = strings.Replace(l, "RUN ", "RUN "+flags.String(), 1)
+		}
+		r[i] = l
+	}
+	return r
 }
 
 func writeLines(w io.Writer, lines []string) error {
@@ -638,37 +1057,98 @@ func DockerPush(ff *FuncFile) error {
 	if err != nil {
 		return err
 	}
+	engine, err := Engine()
+	if err != nil {
+		return err
+	}
 	fmt.Printf("Pushing %v to docker registry...", ff.ImageName())
-	cmd := exec.Command("docker", "push", ff.ImageName())
+	cmd := engine.Command("push", ff.ImageName())
 	cmd.Stderr = os.Stderr
 	cmd.Stdout = os.Stdout
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("error running docker push, are you logged into docker?: %v", err)
+		return fmt.Errorf("error running %s push, are you logged into the registry?: %v", engine.Name, err)
 	}
 	return nil
 }
 
 // DockerPush pushes to docker registry.
 func DockerPushV20180708(ff *FuncFileV20180708) error {
+	return DockerPushV20180708To(StdBuildOutput(), ff)
+}
+
+// DockerPushV20180708To pushes the image of ff to its registry, printing the progress of the push to out
+func DockerPushV20180708To(out BuildOutput, ff *FuncFileV20180708) error {
 	err := ValidateFullImageName(ff.ImageNameV20180708())
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Pushing %v to docker registry...", ff.ImageNameV20180708())
-	cmd := exec.Command("docker", "push", ff.ImageNameV20180708())
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
+	engine, err := Engine()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out.Stdout, "Pushing %v to docker registry...", ff.ImageNameV20180708())
+	cmd := engine.Command("push", ff.ImageNameV20180708())
+	cmd.Stderr = out.Stderr
+	cmd.Stdout = out.Stdout
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("error running docker push, are you logged into docker?: %v", err)
+		return fmt.Errorf("error running %s push, are you logged into the registry?: %v", engine.Name, err)
 	}
 	return nil
 }
 
+// ImageRepoDigest returns the repo@sha256:... reference of a pushed image, which unlike its tag
+// cannot be moved to a different image.
+func ImageRepoDigest(image string) (string, error) {
+	engine, err := Engine()
+	if err != nil {
+		return "", err
+	}
+	out, err := engine.Command("image", "inspect", "--format", "{{json .RepoDigests}}", image).Output()
+	if err != nil {
+		return "", fmt.Errorf("error inspecting image %s: %v", image, err)
+	}
+	var digests []string
+	if err := json.Unmarshal(out, &digests); err != nil {
+		return "", fmt.Errorf("error parsing digests of image %s: %v", image, err)
+	}
+	repo := ImageRepository(image)
+	for _, d := range digests {
+		if strings.HasPrefix(d, repo+"@") {
+			return d, nil
+		}
+	}
+	return "", fmt.Errorf("no digest found for image %s, it must be pushed first", image)
+}
+
+// ManifestDigest returns the repo@sha256:... reference of the manifest, or manifest list, that image points to
+// in its registry. Unlike ImageRepoDigest it works for images pushed by buildx, which docker has no local copy of.
+func ManifestDigest(image string) (string, error) {
+	out, err := exec.Command("docker", "buildx", "imagetools", "inspect", "--format", "{{.Manifest.Digest}}", image).Output()
+	if err != nil {
+		return "", fmt.Errorf("error inspecting image %s: %v", image, err)
+	}
+	digest := strings.TrimSpace(string(out))
+	if !strings.HasPrefix(digest, "sha256:") {
+		return "", fmt.Errorf("no digest found for image %s, it must be pushed first", image)
+	}
+	return ImageRepository(image) + "@" + digest, nil
+}
+
+// ImageRepository returns image without its tag or digest
+func ImageRepository(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
 // ValidateFullImageName validates that the full image name (REGISTRY/name:tag) is allowed for push
 // remember that private registries must be supported here
 func ValidateFullImageName(n string) error {
 	parts := strings.Split(n, "/")
-	fmt.Println("Parts: ", parts)
 	if len(parts) < 2 {
 		return errors.New("image name must have a dockerhub owner or private registry. Be sure to set FN_REGISTRY env var, pass in --registry or configure your context file")
 
@@ -676,8 +1156,11 @@ func ValidateFullImageName(n string) error {
 	return ValidateTagImageName(n)
 }
 
-// ValidateTagImageName validates that the last part of the image name (name:tag) is allowed for create/update
+// ValidateTagImageName validates that the last part of the image name (name:tag or name@digest) is allowed for create/update
 func ValidateTagImageName(n string) error {
+	if strings.Contains(n, "@sha256:") {
+		return nil
+	}
 	parts := strings.Split(n, "/")
 	lastParts := strings.Split(parts[len(parts)-1], ":")
 	if len(lastParts) != 2 {
@@ -748,34 +1231,39 @@ func GetFuncYamlVersion(oldFF map[string]interface{}) int {
 
 // UserConfirmedMultiResourceDeletion will prompt the user for confirmation to delete all the the resources
 func UserConfirmedMultiResourceDeletion(aps []*modelsv2.App, fns []*modelsv2.Fn, trs []*modelsv2.Trigger) bool {
+	return ConfirmMultiResourceDeletion(os.Stdin, os.Stdout, aps, fns, trs)
+}
+
+// ConfirmMultiResourceDeletion prompts on out for confirmation, read from in, to delete all the resources
+func ConfirmMultiResourceDeletion(in io.Reader, out io.Writer, aps []*modelsv2.App, fns []*modelsv2.Fn, trs []*modelsv2.Trigger) bool {
 
 	apsLen := len(aps)
 	fnsLen := len(fns)
 	trsLen := len(trs)
 
-	fmt.Println("You are about to delete the following resources:")
+	fmt.Fprintln(out, "You are about to delete the following resources:")
 	if apsLen > 0 {
-		fmt.Println("   Applications:", apsLen)
+		fmt.Fprintln(out, "   Applications:", apsLen)
 	}
 	if fnsLen > 0 {
-		fmt.Println("   Functions:   ", fnsLen)
+		fmt.Fprintln(out, "   Functions:   ", fnsLen)
 	}
 	if trsLen > 0 {
-		fmt.Println("   Triggers:    ", trsLen)
+		fmt.Fprintln(out, "   Triggers:    ", trsLen)
 	}
-	fmt.Println("This operation cannot be undone")
-	fmt.Printf("Do you wish to proceed? Y/N: ")
-	reader := bufio.NewReader(os.Stdin)
+	fmt.Fprintln(out, "This operation cannot be undone")
+	fmt.Fprintf(out, "Do you wish to proceed? Y/N: ")
+	reader := bufio.NewReader(in)
 	input, _ := reader.ReadString('\n')
 	input = strings.TrimSpace(input)
 	if strings.ToLower(input) == "y" {
 		return true
 	} else if strings.ToLower(input) == "n" {
-		fmt.Println("Cancelling delete")
+		fmt.Fprintln(out, "Cancelling delete")
 		return false
 	} else {
-		fmt.Println("Unrecognised input, should be Y/N")
-		fmt.Println("Cancelling delete")
+		fmt.Fprintln(out, "Unrecognised input, should be Y/N")
+		fmt.Fprintln(out, "Cancelling delete")
 		return false
 	}
 	return true
@@ -800,6 +1288,11 @@ func ListFnsAndTriggersInApp(c *cli.Context, client *fnclient.Fn, app *modelsv2.
 
 //DeleteFunctions deletes all the functions provided to it. if provided nil it is a no-op
 func DeleteFunctions(c *cli.Context, client *fnclient.Fn, fns []*modelsv2.Fn) error {
+	return DeleteFunctionsTo(os.Stdout, c, client, fns)
+}
+
+// DeleteFunctionsTo deletes all the functions provided to it, printing each deletion to out
+func DeleteFunctionsTo(out io.Writer, c *cli.Context, client *fnclient.Fn, fns []*modelsv2.Fn) error {
 	if fns == nil {
 		return nil
 	}
@@ -810,13 +1303,18 @@ func DeleteFunctions(c *cli.Context, client *fnclient.Fn, fns []*modelsv2.Fn) er
 		if err != nil {
 			return fmt.Errorf("Failed to delete Function %s: %s", fn.Name, err)
 		}
-		fmt.Println("Function ", fn.Name, " deleted")
+		fmt.Fprintln(out, "Function ", fn.Name, " deleted")
 	}
 	return nil
 }
 
 //DeleteTriggers deletes all the triggers provided to it. if provided nil it is a no-op
 func DeleteTriggers(c *cli.Context, client *fnclient.Fn, triggers []*modelsv2.Trigger) error {
+	return DeleteTriggersTo(os.Stdout, c, client, triggers)
+}
+
+// DeleteTriggersTo deletes all the triggers provided to it, printing each deletion to out
+func DeleteTriggersTo(out io.Writer, c *cli.Context, client *fnclient.Fn, triggers []*modelsv2.Trigger) error {
 	if triggers == nil {
 		return nil
 	}
@@ -827,12 +1325,12 @@ func DeleteTriggers(c *cli.Context, client *fnclient.Fn, triggers []*modelsv2.Tr
 		if err != nil {
 			return fmt.Errorf("Failed to Delete trigger %s: %s", t.Name, err)
 		}
-		fmt.Println("Trigger ", t.Name, " deleted")
+		fmt.Fprintln(out, "Trigger ", t.Name, " deleted")
 	}
 	return nil
 }
 
-//ListFnsInApp gets all the functions associated with an app
+//ListFnsInApp gets all the functions associated with an app, up to the "n" flag if the command has one
 func ListFnsInApp(c *cli.Context, client *fnclient.Fn, app *modelsv2.App) ([]*modelsv2.Fn, error) {
 	params := &apifns.ListFnsParams{
 		Context: context.Background(),
@@ -850,7 +1348,7 @@ func ListFnsInApp(c *cli.Context, client *fnclient.Fn, app *modelsv2.App) ([]*mo
 
 		resFns = append(resFns, resp.Payload.Items...)
 		howManyMore := n - int64(len(resFns)+len(resp.Payload.Items))
-		if howManyMore <= 0 || resp.Payload.NextCursor == "" {
+		if (n > 0 && howManyMore <= 0) || resp.Payload.NextCursor == "" {
 			break
 		}
 
@@ -860,7 +1358,7 @@ func ListFnsInApp(c *cli.Context, client *fnclient.Fn, app *modelsv2.App) ([]*mo
 	return resFns, nil
 }
 
-//ListTriggersInFunc gets all the triggers associated with a function
+//ListTriggersInFunc gets all the triggers associated with a function, up to the "n" flag if the command has one
 func ListTriggersInFunc(c *cli.Context, client *fnclient.Fn, fn *modelsv2.Fn) ([]*modelsv2.Trigger, error) {
 	params := &apitriggers.ListTriggersParams{
 		Context: context.Background(),
@@ -878,7 +1376,7 @@ func ListTriggersInFunc(c *cli.Context, client *fnclient.Fn, fn *modelsv2.Fn) ([
 
 		resTriggers = append(resTriggers, resp.Payload.Items...)
 		howManyMore := n - int64(len(resTriggers)+len(resp.Payload.Items))
-		if howManyMore <= 0 || resp.Payload.NextCursor == "" {
+		if (n > 0 && howManyMore <= 0) || resp.Payload.NextCursor == "" {
 			break
 		}
 		params.Cursor = &resp.Payload.NextCursor
@@ -893,8 +1391,12 @@ func DockerRunInitImage(initImage string, fName string) error {
 	args = append(args, proxyArgs()...)
 	args = append(args, initImage)
 
-	fmt.Printf("Executing docker command: %s\n", strings.Join(args, " "))
-	cmd := exec.Command("docker", args...)
+	engine, err := Engine()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Executing %s command: %s\n", engine.Name, strings.Join(args, " "))
+	cmd := engine.Command(args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 	stdout, err := cmd.StdoutPipe()
@@ -17,9 +17,18 @@
 package common
 
 import (
+	"flag"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/fnproject/cli/langs"
+	"github.com/urfave/cli"
 )
 
 func TestValidateImageName(t *testing.T) {
@@ -31,6 +40,7 @@ func TestValidateImageName(t *testing.T) {
 		{name: "sally/img:0.0.1", expectedErr: ""},
 		{name: "img:0.0.1", expectedErr: "image name must have a dockerhub owner or private registry. Be sure to set FN_REGISTRY env var, pass in --registry or configure your context file"},
 		{name: "owner/img", expectedErr: "image name must have a tag"},
+		{name: "registry:5000/owner/img@sha256:0d9e", expectedErr: ""},
 	}
 	for _, c := range testCases {
 		t.Run(c.name, func(t *testing.T) {
@@ -45,6 +55,172 @@ func TestValidateImageName(t *testing.T) {
 	}
 }
 
+func TestImageRepository(t *testing.T) {
+	for image, expected := range map[string]string{
+		"owner/img:0.0.1":                     "owner/img",
+		"registry:5000/owner/img:0.0.1":       "registry:5000/owner/img",
+		"registry:5000/owner/img":             "registry:5000/owner/img",
+		"registry:5000/owner/img@sha256:0d9e": "registry:5000/owner/img",
+	} {
+		if repo := ImageRepository(image); repo != expected {
+			t.Fatalf("expected repository of %s to be %s, got %s", image, expected, repo)
+		}
+	}
+}
+
+func TestBuildSecretIDs(t *testing.T) {
+	ids, err := buildSecretIDs([]string{"id=npmrc,src=.npmrc", "src=settings.xml,id=maven-settings"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"npmrc", "maven-settings"}; !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("expected %v, got %v", expected, ids)
+	}
+	if _, err := buildSecretIDs([]string{"src=.npmrc"}); err == nil {
+		t.Fatal("expected an error for a secret without an id")
+	}
+}
+
+func TestBuildSecrets(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	abs := filepath.Join(wd, "secrets", "token")
+	set := flag.NewFlagSet("build", flag.ContinueOnError)
+	cli.StringSliceFlag{Name: "secret"}.Apply(set)
+	if err := set.Parse([]string{"--secret", "id=npmrc,src=.npmrc", "--secret", "id=abs,source=" + abs, "--secret", "id=env,env=TOKEN"}); err != nil {
+		t.Fatal(err)
+	}
+	secrets, err := BuildSecrets(cli.NewContext(cli.NewApp(), set, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"id=npmrc,src=" + filepath.Join(wd, ".npmrc"), "id=abs,source=" + abs, "id=env,env=TOKEN"}
+	if !reflect.DeepEqual(secrets, expected) {
+		t.Fatalf("expected %v, got %v", expected, secrets)
+	}
+}
+
+func TestReadBuildArgFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "build-args")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("# registry\nNPM_REGISTRY=https://npm.example.com\n\n  HTTP_PROXY  \nA=b=c\n")
+	f.Close()
+
+	args, err := ReadBuildArgFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"NPM_REGISTRY=https://npm.example.com", "HTTP_PROXY", "A=b=c"}; !reflect.DeepEqual(args, expected) {
+		t.Fatalf("expected %v, got %v", expected, args)
+	}
+}
+
+func TestParsePlatforms(t *testing.T) {
+	for s, expected := range map[string][]string{
+		"":                             nil,
+		"linux/amd64":                  {"linux/amd64"},
+		"linux/amd64, linux/arm64,":    {"linux/amd64", "linux/arm64"},
+		" linux/arm64 ,,linux/arm/v7 ": {"linux/arm64", "linux/arm/v7"},
+	} {
+		if platforms := ParsePlatforms(s); !reflect.DeepEqual(platforms, expected) {
+			t.Fatalf("expected platforms of %q to be %v, got %v", s, expected, platforms)
+		}
+	}
+}
+
+func TestCheckHelperPlatforms(t *testing.T) {
+	java := langs.GetLangHelper("java")
+	if err := checkHelperPlatforms(java, []string{"linux/amd64", "linux/arm64"}); err != nil {
+		t.Fatalf("expected java to support arm64, got %v", err)
+	}
+	if err := checkHelperPlatforms(java, []string{"linux/s390x"}); err == nil {
+		t.Fatal("expected an error for a platform java has no images for")
+	}
+}
+
+func TestWithRunMounts(t *testing.T) {
+	lines := []string{
+		"ADD pom.xml /function/pom.xml",
+		`RUN ["mvn", "package"]`,
+		"\n\t\t\tRUN pip3 install -r requirements.txt",
+	}
+	expected := []string{
+		"ADD pom.xml /function/pom.xml",
+		`RUN --mount=type=cache,target=/a --mount=type=secret,id=b,target=/b ["mvn", "package"]`,
+		"\n\t\t\tRUN --mount=type=cache,target=/a --mount=type=secret,id=b,target=/b pip3 install -r requirements.txt",
+	}
+	if got := withRunMounts(lines, []string{"type=cache,target=/a", "type=secret,id=b,target=/b"}); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+	if got := withRunMounts(lines, nil); !reflect.DeepEqual(got, lines) {
+		t.Fatalf("expected lines without mounts to be unchanged, got %q", got)
+	}
+}
+
+func TestIsWithinDir(t *testing.T) {
+	dir := filepath.Join("apps", "hello")
+	for path, expected := range map[string]bool{
+		dir:                                   true,
+		filepath.Join(dir, "func.yaml"):       true,
+		filepath.Join("apps", "hello-world"):  false,
+		filepath.Join("apps", "..hello"):      false,
+		filepath.Join(dir, "..", "func.yaml"): false,
+	} {
+		if IsWithinDir(path, dir) != expected {
+			t.Errorf("expected IsWithinDir(%q, %q) to be %v", path, dir, expected)
+		}
+	}
+}
+
+func TestDockerfileCacheMounts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dockerfile-cache-mounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestFile(t, dir, "requirements.txt", "fdk")
+
+	for _, tc := range []struct {
+		runtime   string
+		runMounts bool
+		contains  []string
+		missing   []string
+	}{
+		{runtime: "python", contains: []string{"--no-cache-dir"}, missing: []string{"--mount"}},
+		{runtime: "python", runMounts: true, contains: []string{"--mount=type=cache,target=/root/.cache/pip"}, missing: []string{"--no-cache-dir"}},
+		{runtime: "java", contains: []string{"-Dmaven.repo.local=/usr/share/maven/ref/repository"}, missing: []string{"--mount"}},
+		// the repository the build image comes with isn't hidden by the cache mount
+		{runtime: "java", runMounts: true,
+			contains: []string{"-Dmaven.repo.local=/root/.m2/repository", "--mount=type=cache,target=/root/.m2/repository"},
+			missing:  []string{"target=/usr/share/maven/ref/repository"}},
+		{runtime: "kotlin", runMounts: true,
+			contains: []string{"-Dmaven.repo.local=/root/.m2/repository", "--mount=type=cache,target=/root/.m2/repository"},
+			missing:  []string{"target=/usr/share/maven/ref/repository"}},
+	} {
+		helper := langs.GetLangHelper(tc.runtime)
+		lines, err := dockerfileLinesV20180708(helper, dir, &FuncFileV20180708{Runtime: tc.runtime, Cmd: "handler", Build_image: "build", Run_image: "run"}, tc.runMounts, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dockerfile := strings.Join(lines, "\n")
+		for _, c := range tc.contains {
+			if !strings.Contains(dockerfile, c) {
+				t.Errorf("expected the %s Dockerfile with run mounts %v to contain %q, got:\n%s", tc.runtime, tc.runMounts, c, dockerfile)
+			}
+		}
+		for _, m := range tc.missing {
+			if strings.Contains(dockerfile, m) {
+				t.Errorf("expected the %s Dockerfile with run mounts %v not to contain %q, got:\n%s", tc.runtime, tc.runMounts, m, dockerfile)
+			}
+		}
+	}
+}
+
 func Test_proxyArgs(t *testing.T) {
 	tests := []struct {
 		name string
@@ -84,3 +260,22 @@ func Test_proxyArgs(t *testing.T) {
 		}
 	}
 }
+
+func TestRunBuildStepTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("build steps run with /bin/sh")
+	}
+	// the shell's children hold its output open, so they have to be killed too for the step to stop
+	start := time.Now()
+	err := runBuildStep(StdBuildOutput(), false, os.TempDir(), BuildStep{Run: "sleep 10 && echo done", Timeout: "200ms"})
+	if err == nil || !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Fatalf("expected the step to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the step to stop at its timeout, it took %v", elapsed)
+	}
+
+	if err := runBuildStep(StdBuildOutput(), false, os.TempDir(), BuildStep{Run: "true && echo done", Timeout: "10s"}); err != nil {
+		t.Fatalf("expected the step to finish within its timeout, got %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fnproject/cli/config"
+	models "github.com/fnproject/fn_go/modelsv2"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// LocalStateDirName is the directory, relative to a function or app, that holds local fn state
+	LocalStateDirName = ".fn"
+
+	deployStateFileName = "deploy-state.yaml"
+
+	// ImageTagAnnotation holds the tag a function image was deployed from when the image is pinned to its digest
+	ImageTagAnnotation = "fnproject.io/cli/imageTag"
+
+	// MaxFuncRevisions is the number of earlier revisions of a function kept for rollback
+	MaxFuncRevisions = 10
+)
+
+// FuncDeployState is what was last deployed for a function
+type FuncDeployState struct {
+	Digest     string    `yaml:"digest" json:"digest"`
+	Image      string    `yaml:"image" json:"image"`
+	DeployedAt time.Time `yaml:"deployed_at" json:"deployed_at"`
+}
+
+// FuncRevision is the image, config and limits of a function before it was replaced by a deploy or rollback
+type FuncRevision struct {
+	Image       string            `yaml:"image" json:"image"`
+	Memory      uint64            `yaml:"memory,omitempty" json:"memory,omitempty"`
+	Timeout     *int32            `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	IdleTimeout *int32            `yaml:"idle_timeout,omitempty" json:"idle_timeout,omitempty"`
+	Config      map[string]string `yaml:"config,omitempty" json:"config,omitempty"`
+	// ImageTag is the ImageTagAnnotation of a function whose image was pinned to its digest
+	ImageTag   string    `yaml:"image_tag,omitempty" json:"image_tag,omitempty"`
+	ReplacedAt time.Time `yaml:"replaced_at" json:"replaced_at"`
+}
+
+// NewFuncRevision returns the revision of fn as it is on the server
+func NewFuncRevision(fn *models.Fn) *FuncRevision {
+	tag, _ := fn.Annotations[ImageTagAnnotation].(string)
+	return &FuncRevision{
+		Image:       fn.Image,
+		Memory:      fn.Memory,
+		Timeout:     fn.Timeout,
+		IdleTimeout: fn.IdleTimeout,
+		Config:      fn.Config,
+		ImageTag:    tag,
+		ReplacedAt:  time.Now(),
+	}
+}
+
+// RollbackFn returns the update that restores r over current, the function as it is on the server
+func (r *FuncRevision) RollbackFn(current *models.Fn) *models.Fn {
+	// config and annotations are merged by the server, so those added since the revision are unset explicitly
+	config := make(map[string]string, len(r.Config))
+	for k := range current.Config {
+		config[k] = ""
+	}
+	for k, v := range r.Config {
+		config[k] = v
+	}
+	var annotations map[string]interface{}
+	if r.ImageTag != "" {
+		annotations = map[string]interface{}{ImageTagAnnotation: r.ImageTag}
+	} else if _, ok := current.Annotations[ImageTagAnnotation]; ok {
+		annotations = map[string]interface{}{ImageTagAnnotation: ""}
+	}
+	return &models.Fn{
+		Image:       r.Image,
+		Memory:      r.Memory,
+		Timeout:     r.Timeout,
+		IdleTimeout: r.IdleTimeout,
+		Config:      config,
+		Annotations: annotations,
+	}
+}
+
+// sameAs reports whether r and o would restore the same function
+func (r *FuncRevision) sameAs(o *FuncRevision) bool {
+	a, b := *r, *o
+	a.ReplacedAt, b.ReplacedAt = time.Time{}, time.Time{}
+	if len(a.Config) == 0 && len(b.Config) == 0 {
+		a.Config, b.Config = nil, nil
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// DeployState records the functions deployed from an app tree, keyed by context, app and function name.
+// It is safe for concurrent use.
+type DeployState struct {
+	path string
+	mu   sync.Mutex
+
+	Functions map[string]*FuncDeployState `yaml:"functions" json:"functions"`
+	History   map[string][]*FuncRevision  `yaml:"history,omitempty" json:"history,omitempty"`
+}
+
+// LoadDeployState reads the deploy state of the app tree dir is in, kept under the app root, the nearest
+// directory at or above dir with an app file, so that deploys and rollbacks from anywhere in the tree
+// share it. Outside of an app tree it is kept under dir. A missing state file yields an empty state.
+func LoadDeployState(dir string) (*DeployState, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if appfile, err := findAppfileAbove(dir); err == nil {
+		dir = filepath.Dir(appfile)
+	}
+	s := &DeployState{
+		path:      filepath.Join(dir, LocalStateDirName, deployStateFileName),
+		Functions: map[string]*FuncDeployState{},
+		History:   map[string][]*FuncRevision{},
+	}
+
+	b, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s for parsing. Error: %v", s.path, err)
+	}
+	if err = yaml.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("could not parse %s. Error: %v", s.path, err)
+	}
+	if s.Functions == nil {
+		s.Functions = map[string]*FuncDeployState{}
+	}
+	if s.History == nil {
+		s.History = map[string][]*FuncRevision{}
+	}
+	return s, nil
+}
+
+func deployStateKey(appName, fnName string) string {
+	return fmt.Sprintf("%s/%s/%s", viper.GetString(config.CurrentContext), appName, fnName)
+}
+
+// Get returns the last deployed state of a function in the current context, or nil if there is none
+func (s *DeployState) Get(appName, fnName string) *FuncDeployState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Functions[deployStateKey(appName, fnName)]
+}
+
+// Set records the deployed state of a function in the current context and writes the state file
+func (s *DeployState) Set(appName, fnName string, fs *FuncDeployState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Functions[deployStateKey(appName, fnName)] = fs
+	return s.save()
+}
+
+// Forget removes the deployed state of a function in the current context, so that its next deploy is
+// not skipped, and writes the state file
+func (s *DeployState) Forget(appName, fnName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.Functions, deployStateKey(appName, fnName))
+	return s.save()
+}
+
+// Revisions returns the earlier revisions of a function in the current context, most recent first
+func (s *DeployState) Revisions(appName, fnName string) []*FuncRevision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.History[deployStateKey(appName, fnName)]
+}
+
+// AddRevision records the revision a function had before it was replaced and writes the state file. A
+// revision that matches the most recent one is not added again, and only MaxFuncRevisions are kept.
+func (s *DeployState) AddRevision(appName, fnName string, rev *FuncRevision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := deployStateKey(appName, fnName)
+	revisions := s.History[key]
+	if len(revisions) > 0 && revisions[0].sameAs(rev) {
+		return nil
+	}
+	revisions = append([]*FuncRevision{rev}, revisions...)
+	if len(revisions) > MaxFuncRevisions {
+		revisions = revisions[:MaxFuncRevisions]
+	}
+	s.History[key] = revisions
+	return s.save()
+}
+
+// save writes the state file, the caller must hold s.mu
+func (s *DeployState) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), config.ReadWritePerms); err != nil {
+		return fmt.Errorf("error creating %s directory %v", LocalStateDirName, err)
+	}
+	b, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.path, b, os.FileMode(0644))
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	models "github.com/fnproject/fn_go/modelsv2"
+)
+
+func TestDeployStateRevisions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deploystate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	state, err := LoadDeployState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < MaxFuncRevisions+2; i++ {
+		rev := &FuncRevision{Image: fmt.Sprintf("fn:0.0.%d", i), Config: map[string]string{"A": "1"}}
+		if err := state.AddRevision("app", "fn", rev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := state.AddRevision("app", "fn", &FuncRevision{Image: fmt.Sprintf("fn:0.0.%d", MaxFuncRevisions+1), Config: map[string]string{"A": "1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err = LoadDeployState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions := state.Revisions("app", "fn")
+	if len(revisions) != MaxFuncRevisions {
+		t.Fatalf("expected %d revisions, got %d", MaxFuncRevisions, len(revisions))
+	}
+	if latest := revisions[0].Image; latest != fmt.Sprintf("fn:0.0.%d", MaxFuncRevisions+1) {
+		t.Fatalf("expected most recent revision first, got %s", latest)
+	}
+	if len(state.Revisions("app", "other")) != 0 {
+		t.Fatal("expected no revisions for another function")
+	}
+}
+
+func TestDeployStateAppRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deploystate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestFile(t, dir, "app.yaml", "name: app\n")
+	writeTestFile(t, dir, "hello/func.yaml", "name: hello\n")
+
+	// a deploy from the function directory is seen from the app root, as a deploy --all would record it
+	state, err := LoadDeployState(filepath.Join(dir, "hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.AddRevision("app", "hello", &FuncRevision{Image: "hello:0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if !Exists(filepath.Join(dir, LocalStateDirName, deployStateFileName)) {
+		t.Fatal("expected the state to be kept under the app root")
+	}
+	state, err = LoadDeployState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revisions := state.Revisions("app", "hello"); len(revisions) != 1 {
+		t.Fatalf("expected the revision recorded from the function directory, got %v", revisions)
+	}
+}
+
+func TestFuncRevisionRollbackFn(t *testing.T) {
+	pinned := &models.Fn{
+		Image:       "registry/fn@sha256:0d9e",
+		Config:      map[string]string{"A": "1"},
+		Annotations: map[string]interface{}{ImageTagAnnotation: "registry/fn:0.0.1"},
+	}
+	rev := NewFuncRevision(pinned)
+	if rev.ImageTag != "registry/fn:0.0.1" {
+		t.Fatalf("expected the image tag to be recorded, got %+v", rev)
+	}
+
+	// rolling back to a pinned revision restores its tag
+	current := &models.Fn{
+		Image:       "registry/fn@sha256:1a2b",
+		Config:      map[string]string{"A": "2", "B": "2"},
+		Annotations: map[string]interface{}{ImageTagAnnotation: "registry/fn:0.0.2"},
+	}
+	update := rev.RollbackFn(current)
+	if update.Image != pinned.Image || !reflect.DeepEqual(update.Config, map[string]string{"A": "1", "B": ""}) {
+		t.Errorf("expected the image and config of the revision, got %+v", update)
+	}
+	if tag := update.Annotations[ImageTagAnnotation]; tag != "registry/fn:0.0.1" {
+		t.Errorf("expected the tag of the revision, got %v", tag)
+	}
+
+	// rolling back to an unpinned revision removes the tag of the later deploy
+	update = NewFuncRevision(&models.Fn{Image: "registry/fn:0.0.1"}).RollbackFn(current)
+	if tag, ok := update.Annotations[ImageTagAnnotation]; !ok || tag != "" {
+		t.Errorf("expected the tag to be removed, got %v", update.Annotations)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fnproject/cli/langs"
+	"gopkg.in/yaml.v2"
+)
+
+// directories that never contribute to the content of a function image
+var digestSkipDirs = map[string]bool{
+	".git": true,
+	".fn":  true,
+}
+
+// FuncDigestV20180708 returns a digest of everything that goes into the image of the function at fpath:
+// the func file (minus its version, which is bumped on every deploy), the build args, the Dockerfile
+// lines a language helper would generate and every file in the function directory that the build doesn't
+// ignore, so that local build output such as target/ doesn't count as a change. Sub directories that
+// contain their own func file belong to another function and are not included. Functions built from a
+// build context above their directory also include the app's shared paths in it, and a Dockerfile outside
+// of the function directory.
+func FuncDigestV20180708(fpath string, ff *FuncFileV20180708, buildArgs []string) (string, error) {
+	h := sha256.New()
+	dir := filepath.Dir(fpath)
+
+	unversioned := *ff
+	unversioned.Version = ""
+	b, err := yaml.Marshal(&unversioned)
+	if err != nil {
+		return "", fmt.Errorf("could not encode function file. Error: %v", err)
+	}
+	fmt.Fprintf(h, "funcfile\x00%s\x00", b)
+
+	args := append([]string{}, buildArgs...)
+	sort.Strings(args)
+	fmt.Fprintf(h, "build-args\x00%s\x00", strings.Join(args, "\x00"))
+
+	bc, err := BuildContextV20180708(fpath, ff)
+	if err != nil {
+		return "", err
+	}
+	var helper langs.LangHelper
+	dockerfile := ff.DockerfilePath(dir)
+	if !Exists(dockerfile) && ff.Runtime != FuncfileDockerRuntime {
+		if helper = langs.GetLangHelper(ff.Runtime); helper != nil {
+			fmt.Fprintf(h, "dockerfile\x00%v\x00%s\x00%s\x00", helper.IsMultiStage(),
+				strings.Join(helper.DockerfileBuildCmds(dir), "\n"),
+				strings.Join(helper.DockerfileCopyCmds(dir), "\n"))
+		}
+	} else if abs, err := filepath.Abs(dockerfile); err == nil && !IsWithinDir(abs, bc.FuncDir) {
+		fmt.Fprintf(h, "dockerfile\x00")
+		if err := hashFile(h, dockerfile); err != nil {
+			return "", fmt.Errorf("could not compute digest of %s: %v", dockerfile, err)
+		}
+	}
+
+	// the files left out of the build, as dockerBuildV20180708 leaves them out
+	var ignores []string
+	if helper != nil {
+		ignores, err = dockerignoreLines(bc, helper)
+	} else {
+		ignores, err = userIgnoreLines(bc)
+	}
+	if err != nil {
+		return "", err
+	}
+	m := newIgnoreMatcher(ignores)
+	skip, err := filepath.Abs(fpath)
+	if err != nil {
+		return "", err
+	}
+
+	if err := hashDir(h, bc, m, bc.FuncDir, "", skip); err != nil {
+		return "", fmt.Errorf("could not compute digest of %s: %v", dir, err)
+	}
+	for _, shared := range bc.SharedPaths {
+		if err := hashDir(h, bc, m, shared, bc.rel(shared)+"/", ""); err != nil {
+			return "", fmt.Errorf("could not compute digest of %s: %v", shared, err)
+		}
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashDir writes the path, with prefix, the permissions and the content of every file in dir, a directory
+// in the build context bc, to h, except for skip, those of other functions and those m ignores
+func hashDir(h io.Writer, bc *BuildContext, m *ignoreMatcher, dir, prefix, skip string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && m.ignored(bc.rel(path)) {
+			// files in an ignored directory are only built from if an exception may match them
+			if info.IsDir() && !m.hasExceptions {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			if path == dir {
+				return nil
+			}
+			if digestSkipDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			if _, err := FindFuncfile(path); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || path == skip {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "file\x00%s%s\x00%o\x00", prefix, filepath.ToSlash(rel), info.Mode().Perm())
+		return hashFile(h, path)
+	})
+}
+
+func hashFile(h io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(h, f)
+	return err
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFuncDigestV20180708(t *testing.T) {
+	dir, err := ioutil.TempDir("", "digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fpath := filepath.Join(dir, "func.yaml")
+	ff := &FuncFileV20180708{Schema_version: V20180708, Name: "fn", Version: "0.0.1", Runtime: "docker"}
+	writeTestFile(t, dir, "func.yaml", "")
+	writeTestFile(t, dir, "Dockerfile", "FROM scratch")
+	writeTestFile(t, dir, "src/main.go", "package main")
+
+	digest := func() string {
+		d, err := FuncDigestV20180708(fpath, ff, []string{"A=1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	base := digest()
+
+	ff.Version = "0.0.2"
+	if d := digest(); d != base {
+		t.Fatalf("expected version bump not to change digest, got %s and %s", base, d)
+	}
+
+	writeTestFile(t, dir, "sub/func.yaml", "")
+	writeTestFile(t, dir, "sub/other.go", "package other")
+	writeTestFile(t, dir, ".fn/deploy-state.yaml", "functions: {}")
+	if d := digest(); d != base {
+		t.Fatalf("expected nested function and local state not to change digest, got %s and %s", base, d)
+	}
+
+	// what the build leaves out doesn't count either
+	writeTestFile(t, dir, ".dockerignore", "build")
+	base = digest()
+	writeTestFile(t, dir, "build/main", "binary")
+	if d := digest(); d != base {
+		t.Fatalf("expected ignored build output not to change digest, got %s and %s", base, d)
+	}
+
+	writeTestFile(t, dir, "src/main.go", "package main // changed")
+	if d := digest(); d == base {
+		t.Fatal("expected source change to change digest")
+	}
+	base = digest()
+
+	ff.Memory = 256
+	if d := digest(); d == base {
+		t.Fatal("expected func file change to change digest")
+	}
+}
+
+func TestFuncDigestV20180708LangIgnores(t *testing.T) {
+	dir, err := ioutil.TempDir("", "digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fpath := filepath.Join(dir, "func.yaml")
+	ff := &FuncFileV20180708{Schema_version: V20180708, Name: "fn", Version: "0.0.1", Runtime: "python",
+		Build_image: "fnproject/python:3.11-dev", Run_image: "fnproject/python:3.11"}
+	writeTestFile(t, dir, "func.yaml", "")
+	writeTestFile(t, dir, "func.py", "import fdk")
+	base, err := FuncDigestV20180708(fpath, ff, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// files the language helper leaves out of the build
+	writeTestFile(t, dir, "__pycache__/func.cpython-311.pyc", "bytecode")
+	writeTestFile(t, dir, ".venv/lib/site.py", "")
+	if d, err := FuncDigestV20180708(fpath, ff, nil); err != nil || d != base {
+		t.Fatalf("expected files the build ignores not to change digest, got %s and %s, %v", base, d, err)
+	}
+}
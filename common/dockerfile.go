@@ -0,0 +1,175 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fnproject/cli/langs"
+)
+
+// GenerateDockerfileV20180708 writes the Dockerfile that the language helper of the function at fpath builds
+// it with, and a .dockerignore if there isn't one, into the function directory so that it can be reviewed
+// and customised. Builds use the Dockerfile from then on. If dockerRuntime is set the func file is also
+// switched to the docker runtime. Functions built from a build context above their directory get no
+// .dockerignore, as it would apply to every function built from there.
+func GenerateDockerfileV20180708(fpath string, ff *FuncFileV20180708, cacheMounts, dockerRuntime bool) error {
+	dir := filepath.Dir(fpath)
+	dockerfile := ff.DockerfilePath(dir)
+	if Exists(dockerfile) {
+		return fmt.Errorf("%s already exists", dockerfile)
+	}
+	if ff.Runtime == FuncfileDockerRuntime {
+		return fmt.Errorf("functions with the 'docker' runtime are built from their own Dockerfile")
+	}
+	helper := langs.GetLangHelper(ff.Runtime)
+	if helper == nil {
+		return fmt.Errorf("Cannot generate a Dockerfile, no language helper found for %v", ff.Runtime)
+	}
+
+	// the build and run images are stamped into the func file just as a build would
+	ff, err := imageStampFuncFileV20180708(fpath, ff)
+	if err != nil {
+		return err
+	}
+	bc, err := BuildContextV20180708(fpath, ff)
+	if err != nil {
+		return err
+	}
+	dfLines, err := dockerfileLinesV20180708(helper, dir, ff, cacheMounts, nil)
+	if err != nil {
+		return err
+	}
+	if err := writeLinesToFile(dockerfile, bc.dockerfileLines(dfLines)); err != nil {
+		return err
+	}
+
+	dockerignore := filepath.Join(dir, ".dockerignore")
+	if bc.Dir == bc.FuncDir && !Exists(dockerignore) {
+		ignored, err := dockerignoreLines(bc, helper)
+		if err != nil {
+			return err
+		}
+		if err := writeLinesToFile(dockerignore, ignored); err != nil {
+			return err
+		}
+	}
+
+	if dockerRuntime {
+		// switch the func file without the overlay merged into ff
+		base, err := parseFuncFileV20180708(fpath)
+		if err != nil {
+			return err
+		}
+		base.Runtime = FuncfileDockerRuntime
+		return storeFuncFileV20180708(fpath, base)
+	}
+	return nil
+}
+
+// dockerignoreLines returns the .dockerignore entries of the build of a function: the directories that never
+// contribute to a function image, directories holding other functions, the files helper, which may be nil,
+// doesn't need and then those of userIgnoreLines, which can undo any of them with !
+func dockerignoreLines(bc *BuildContext, helper langs.LangHelper) ([]string, error) {
+	lines := []string{".dockerignore", "Dockerfile", FnIgnoreFile}
+	for d := range digestSkipDirs {
+		lines = append(lines, d)
+	}
+	sort.Strings(lines)
+
+	if bc.rel(bc.FuncDir) != "." {
+		lines = append(lines, bc.inFunc([]string{FnIgnoreFile, LocalStateDirName})...)
+	}
+	if helper != nil {
+		lines = append(lines, bc.inFunc(helper.DockerIgnore(bc.FuncDir))...)
+	}
+
+	err := filepath.Walk(bc.Dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// the function directory and the directories above it hold the function
+		if !info.IsDir() || IsWithinDir(bc.FuncDir, p) {
+			return nil
+		}
+		if digestSkipDirs[info.Name()] {
+			return filepath.SkipDir
+		}
+		if _, err := FindFuncfile(p); err == nil {
+			lines = append(lines, bc.rel(p))
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := userIgnoreLines(bc)
+	if err != nil {
+		return nil, err
+	}
+	return append(lines, user...), nil
+}
+
+// userIgnoreLines returns the entries of the .dockerignore of the build context followed by those of the
+// .fnignore of the function
+func userIgnoreLines(bc *BuildContext) ([]string, error) {
+	dockerignore, err := readIgnoreFile(filepath.Join(bc.Dir, ".dockerignore"))
+	if err != nil {
+		return nil, err
+	}
+	fnignore, err := readIgnoreFile(filepath.Join(bc.FuncDir, FnIgnoreFile))
+	if err != nil {
+		return nil, err
+	}
+	return append(dockerignore, bc.inFunc(fnignore)...), nil
+}
+
+// inFunc returns patterns relative to the function directory relative to the build context instead
+func (bc *BuildContext) inFunc(patterns []string) []string {
+	funcRel := bc.rel(bc.FuncDir)
+	if funcRel == "." {
+		return patterns
+	}
+	r := make([]string, len(patterns))
+	for i, p := range patterns {
+		if strings.HasPrefix(p, "!") {
+			r[i] = "!" + path.Join(funcRel, p[1:])
+		} else {
+			r[i] = path.Join(funcRel, p)
+		}
+	}
+	return r
+}
+
+func writeLinesToFile(path string, lines []string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeLines(f, lines); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
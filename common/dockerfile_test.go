@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateDockerfileV20180708(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dockerfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	read := func(name string) string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	writeTestFile(t, dir, "func.yaml", "schema_version: 20180708\nname: fn\nversion: 0.0.1\nruntime: python\nentrypoint: /python/bin/fdk /function/func.py handler\n")
+	writeTestFile(t, dir, "func.py", "")
+	writeTestFile(t, dir, "sub/func.yaml", "schema_version: 20180708\nname: sub\n")
+
+	fpath, ff, err := FindAndParseFuncFileV20180708(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateDockerfileV20180708(fpath, ff, false, true); err != nil {
+		t.Fatal(err)
+	}
+
+	dockerfile := read("Dockerfile")
+	for _, expected := range []string{"FROM fnproject/python:3.8-dev as build-stage", `ENTRYPOINT ["/python/bin/fdk", "/function/func.py", "handler"]`} {
+		if !strings.Contains(dockerfile, expected) {
+			t.Fatalf("expected Dockerfile to contain %q, got:\n%s", expected, dockerfile)
+		}
+	}
+	if ignored := strings.Fields(read(".dockerignore")); !contains(ignored, "sub") || !contains(ignored, ".git") {
+		t.Fatalf("expected .dockerignore to ignore sub and .git, got %v", ignored)
+	}
+	if _, ff, err = FindAndParseFuncFileV20180708(dir); err != nil {
+		t.Fatal(err)
+	}
+	if ff.Runtime != FuncfileDockerRuntime || ff.Run_image != "fnproject/python:3.8" {
+		t.Fatalf("expected func file with docker runtime and stamped images, got %+v", ff)
+	}
+
+	if err := GenerateDockerfileV20180708(fpath, ff, false, true); err == nil {
+		t.Fatal("expected an error when the Dockerfile already exists")
+	}
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
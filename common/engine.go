@@ -0,0 +1,181 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/fnproject/cli/config"
+	"github.com/spf13/viper"
+)
+
+// DefaultContainerEngine is used when no engine is set in the context or environment
+const DefaultContainerEngine = "docker"
+
+// ContainerEngine is a docker compatible CLI that builds, pushes, runs and inspects images
+type ContainerEngine struct {
+	// Name is the binary of the engine, eg: docker
+	Name string
+	// MinVersion is the oldest version of the engine that can build functions
+	MinVersion string
+	// Buildx is whether the engine can build images for several platforms at once with docker buildx
+	Buildx bool
+	// OCIArchive is whether the engine can save images as OCI archives
+	OCIArchive bool
+
+	// versionFormat is the template for `version --format` that prints the version of the engine
+	versionFormat string
+	// runMountVersion is the first version of the engine whose builds support RUN --mount by default
+	runMountVersion string
+	// ociSaveArgs are the arguments to `save` that make it write an OCI archive
+	ociSaveArgs []string
+}
+
+var containerEngines = map[string]*ContainerEngine{
+	"docker": {Name: "docker", MinVersion: MinRequiredDockerVersion, Buildx: true, versionFormat: "{{.Server.Version}}",
+		runMountVersion: "23.0.0"},
+	"podman": {Name: "podman", MinVersion: "3.0.0", OCIArchive: true, versionFormat: "{{.Client.Version}}",
+		ociSaveArgs: []string{"--format", "oci-archive"}, runMountVersion: "4.0.0"},
+	// nerdctl archives are both docker and OCI archives, and its builds always use BuildKit
+	"nerdctl": {Name: "nerdctl", MinVersion: "0.20.0", OCIArchive: true, versionFormat: "{{.Client.Version}}"},
+}
+
+// Engine returns the container engine set by the container-engine setting of the current context, or
+// the FN_CONTAINER_ENGINE env var, docker by default.
+func Engine() (*ContainerEngine, error) {
+	name := viper.GetString(config.ContainerEngine)
+	if name == "" {
+		name = DefaultContainerEngine
+	}
+	e, ok := containerEngines[name]
+	if !ok {
+		var names []string
+		for n := range containerEngines {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown container engine %s, must be one of %s", name, strings.Join(names, ", "))
+	}
+	return e, nil
+}
+
+// Command returns a command that runs the engine with args
+func (e *ContainerEngine) Command(args ...string) *exec.Cmd {
+	return exec.Command(e.Name, args...)
+}
+
+// VersionCheck returns an error if the engine can't be reached or is older than its MinVersion
+func (e *ContainerEngine) VersionCheck() error {
+	v, err := e.version()
+	if err != nil {
+		return err
+	}
+	vMin, err := semver.NewVersion(e.MinVersion)
+	if err != nil {
+		return fmt.Errorf("our bad, sorry... please make an issue, detailed error: %v", err)
+	}
+	if v.LessThan(*vMin) {
+		return fmt.Errorf("please upgrade your version of %s to %s or greater", e.Name, e.MinVersion)
+	}
+	return nil
+}
+
+// RunMounts reports whether builds by the engine support BuildKit's RUN --mount, for cache and secret mounts.
+// Before docker 23 BuildKit has to be turned on with DOCKER_BUILDKIT=1, and it can always be turned off with
+// DOCKER_BUILDKIT=0.
+func (e *ContainerEngine) RunMounts() bool {
+	minVersion := e.runMountVersion
+	if e.Name == "docker" {
+		switch os.Getenv("DOCKER_BUILDKIT") {
+		case "0":
+			return false
+		case "1":
+			minVersion = "20.10.0"
+		}
+	}
+	if minVersion == "" {
+		return true
+	}
+	v, err := e.version()
+	if err != nil {
+		return false
+	}
+	return !v.LessThan(*semver.New(minVersion))
+}
+
+func (e *ContainerEngine) version() (*semver.Version, error) {
+	out, err := e.Command("version", "--format", e.versionFormat).Output()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot connect to %s, make sure you have it installed and running: %v", e.Name, err)
+	}
+	// dev / test builds append '-ce', trim this
+	trimmed := strings.TrimRightFunc(strings.TrimSpace(string(out)), func(r rune) bool { return r != '.' && !unicode.IsDigit(r) })
+
+	v, err := semver.NewVersion(strings.TrimPrefix(trimmed, "v"))
+	if err != nil {
+		return nil, fmt.Errorf("could not check %s version: %v", e.Name, err)
+	}
+	return v, nil
+}
+
+// Save writes image to an archive at path, an OCI archive if oci is set and a docker archive otherwise
+func (e *ContainerEngine) Save(image, path string, oci bool) error {
+	args := []string{"save", "-o", path}
+	if oci {
+		if !e.OCIArchive {
+			return fmt.Errorf("%s cannot save images as OCI archives", e.Name)
+		}
+		args = append(args, e.ociSaveArgs...)
+	}
+	out, err := e.Command(append(args, image)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error saving image %s to %s: %v: %s", image, path, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Load loads the image archive at path and tags the image it holds as image
+func (e *ContainerEngine) Load(path, image string) error {
+	out, err := e.Command("load", "-i", path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error loading image archive %s: %v: %s", path, err, strings.TrimSpace(string(out)))
+	}
+	// engines print the name, or the ID of untagged images, eg: "Loaded image: owner/img:0.0.1"
+	loaded := ""
+	for _, line := range strings.Split(string(out), "\n") {
+		if i := strings.Index(line, ": "); i >= 0 && strings.HasPrefix(line, "Loaded image") {
+			loaded = strings.TrimSpace(line[i+2:])
+			break
+		}
+	}
+	if loaded == "" {
+		return fmt.Errorf("no image found in archive %s", path)
+	}
+	if loaded == image {
+		return nil
+	}
+	if out, err := e.Command("tag", loaded, image).CombinedOutput(); err != nil {
+		return fmt.Errorf("error tagging image %s as %s: %v: %s", loaded, image, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
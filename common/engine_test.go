@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"testing"
+
+	"github.com/fnproject/cli/config"
+	"github.com/spf13/viper"
+)
+
+func TestEngine(t *testing.T) {
+	defer viper.Set(config.ContainerEngine, nil)
+
+	for name, expected := range map[string]string{
+		"":        "docker",
+		"docker":  "docker",
+		"podman":  "podman",
+		"nerdctl": "nerdctl",
+	} {
+		viper.Set(config.ContainerEngine, name)
+		e, err := Engine()
+		if err != nil {
+			t.Fatalf("expected no error for engine %q, got %v", name, err)
+		}
+		if e.Name != expected {
+			t.Fatalf("expected engine %q to be %s, got %s", name, expected, e.Name)
+		}
+	}
+
+	viper.Set(config.ContainerEngine, "rkt")
+	if _, err := Engine(); err == nil {
+		t.Fatal("expected an error for an unknown engine")
+	}
+}
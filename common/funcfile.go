@@ -131,12 +131,86 @@ type FuncFileV20180708 struct {
 
 	SigningDetails SigningDetails `yaml:"signing_details,omitempty" json:"signing_details,omitempty""`
 
-	Build []string `yaml:"build,omitempty" json:"build,omitempty"`
+	Build []BuildStep `yaml:"build,omitempty" json:"build,omitempty"`
+	// Platforms to build the image for with docker buildx, eg: linux/amd64, the host platform if empty
+	Platforms []string `yaml:"platforms,omitempty" json:"platforms,omitempty"`
+	// Build_context is the directory, relative to the function, that the image is built from, the function
+	// directory if empty. It must contain the function directory.
+	Build_context string `yaml:"build_context,omitempty" json:"build_context,omitempty"`
+	// Dockerfile is the path, relative to the function, of the Dockerfile the image is built with, the
+	// Dockerfile in the function directory or one generated by the language helper if empty
+	Dockerfile string `yaml:"dockerfile,omitempty" json:"dockerfile,omitempty"`
+	// SBOM is the format, spdx or cyclonedx, of a software bill of materials written to .fn on each build
+	SBOM string `yaml:"sbom,omitempty" json:"sbom,omitempty"`
 
 	Expects  Expects   `yaml:"expects,omitempty" json:"expects,omitempty"`
 	Triggers []Trigger `yaml:"triggers,omitempty" json:"triggers,omitempty"`
 }
 
+// DockerfilePath returns the path of the Dockerfile of the function in dir, which a language helper
+// generates if it doesn't exist
+func (ff *FuncFileV20180708) DockerfilePath(dir string) string {
+	if ff.Dockerfile != "" {
+		return filepath.Join(dir, ff.Dockerfile)
+	}
+	return filepath.Join(dir, "Dockerfile")
+}
+
+// BuildStep is a command in the build section of a FuncFileV20180708, run on the host before the image is
+// built. Steps that only have a command can be written as just the command.
+type BuildStep struct {
+	Run string            `yaml:"run" json:"run"`
+	Env map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	// Dir is the directory the command is run in, relative to the function directory
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+	// Timeout is how long the command may run for, eg: 5m
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// buildStep has the fields of BuildStep without its marshalling
+type buildStep BuildStep
+
+func (s *BuildStep) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&s.Run); err == nil {
+		return nil
+	}
+	return unmarshal((*buildStep)(s))
+}
+
+func (s BuildStep) MarshalYAML() (interface{}, error) {
+	if s.onlyRun() {
+		return s.Run, nil
+	}
+	return buildStep(s), nil
+}
+
+func (s *BuildStep) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &s.Run); err == nil {
+		return nil
+	}
+	return json.Unmarshal(b, (*buildStep)(s))
+}
+
+func (s BuildStep) MarshalJSON() ([]byte, error) {
+	if s.onlyRun() {
+		return json.Marshal(s.Run)
+	}
+	return json.Marshal(buildStep(s))
+}
+
+func (s BuildStep) onlyRun() bool {
+	return len(s.Env) == 0 && s.Dir == "" && s.Timeout == ""
+}
+
+// buildSteps returns the build steps of commands
+func buildSteps(commands []string) []BuildStep {
+	var steps []BuildStep
+	for _, c := range commands {
+		steps = append(steps, BuildStep{Run: c})
+	}
+	return steps
+}
+
 // Trigger represents a trigger for a FuncFileV20180708
 type Trigger struct {
 	Name   string `yaml:"name,omitempty" json:"name,omitempty"`
@@ -300,7 +374,21 @@ func LoadFuncFileV20180708(path string) (string, *FuncFileV20180708, error) {
 	return FindAndParseFuncFileV20180708(path)
 }
 
-func ParseFuncFileV20180708(path string) (ff *FuncFileV20180708, err error) {
+// ParseFuncFileV20180708 parses the func file at path, merging the overlay for the current context over it
+func ParseFuncFileV20180708(path string) (*FuncFileV20180708, error) {
+	ff, err := parseFuncFileV20180708(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyOverlay(path, ff); err != nil {
+		return nil, err
+	}
+	return ff, nil
+}
+
+// parseFuncFileV20180708 parses the func file at path as it is written, without any overlay. Use it when
+// the func file is to be written back.
+func parseFuncFileV20180708(path string) (ff *FuncFileV20180708, err error) {
 	ext := filepath.Ext(path)
 	switch ext {
 	case ".json":
@@ -400,7 +488,7 @@ func MergeFuncFileInitYAML(path string, ff *FuncFileV20180708) error {
 	//         config, cpus, idle_timeout, memory, name, path, timeout, type, triggers, version
 	//     Add the following from the init-image:
 	//         build, build_image, cmd, content_type, entrypoint, expects, headers, run_image, runtime
-	ff.Build = initFf.Build
+	ff.Build = buildSteps(initFf.Build)
 	ff.Build_image = initFf.BuildImage
 	ff.Cmd = initFf.Cmd
 	ff.Content_type = initFf.ContentType
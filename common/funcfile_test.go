@@ -22,6 +22,8 @@ import (
 	"path"
 	"reflect"
 	"testing"
+
+	"gopkg.in/yaml.v2"
 )
 
 func TestMergeFuncFileInitYAML(t *testing.T) {
@@ -119,3 +121,33 @@ func createInitYAML(contents string) (string, string) {
 
 	return folder, filePath
 }
+
+func TestBuildStepYAML(t *testing.T) {
+	in := `build:
+- make
+- run: npm test
+  env:
+    CI: "true"
+  dir: web
+  timeout: 5m
+`
+	var ff FuncFileV20180708
+	if err := yaml.Unmarshal([]byte(in), &ff); err != nil {
+		t.Fatal(err)
+	}
+	expected := []BuildStep{
+		{Run: "make"},
+		{Run: "npm test", Env: map[string]string{"CI": "true"}, Dir: "web", Timeout: "5m"},
+	}
+	if !reflect.DeepEqual(ff.Build, expected) {
+		t.Fatalf("expected build steps %v, got %v", expected, ff.Build)
+	}
+
+	out, err := yaml.Marshal(FuncFileV20180708{Build: ff.Build})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != in {
+		t.Fatalf("expected build steps to be written as\n%s\ngot\n%s", in, out)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/fnproject/cli/config"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// overlayPath returns the overlay of the app or func file at path for the current context, eg. func.prod.yaml
+// for func.yaml when the prod context is in use. The overlay may not exist.
+func overlayPath(path string) string {
+	ctx := viper.GetString(config.CurrentContext)
+	if ctx == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + ctx + ext
+}
+
+// applyOverlay decodes the overlay of the file at path for the current context over v, if there is one.
+// Fields set in the overlay replace those of v, except for maps such as config and annotations whose
+// keys are merged.
+func applyOverlay(path string, v interface{}) error {
+	opath := overlayPath(path)
+	if opath == "" || !Exists(opath) {
+		return nil
+	}
+	b, err := ioutil.ReadFile(opath)
+	if err != nil {
+		return fmt.Errorf("could not open %s for parsing. Error: %v", opath, err)
+	}
+	if filepath.Ext(opath) == ".json" {
+		err = json.Unmarshal(b, v)
+	} else {
+		err = yaml.Unmarshal(b, v)
+	}
+	if err != nil {
+		return fmt.Errorf("could not parse %s. Error: %v", opath, err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fnproject/cli/config"
+	"github.com/spf13/viper"
+)
+
+func TestFuncFileOverlay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "overlay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, dir, "func.yaml", "schema_version: 20180708\nname: fn\nversion: 0.0.1\nmemory: 128\nconfig:\n  A: base\n  B: base\n")
+	writeTestFile(t, dir, "func.prod.yaml", "memory: 1024\nconfig:\n  B: prod\n  C: prod\n")
+	fpath := filepath.Join(dir, "func.yaml")
+
+	defer viper.Set(config.CurrentContext, viper.GetString(config.CurrentContext))
+	viper.Set(config.CurrentContext, "dev")
+	ff, err := ParseFuncFileV20180708(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ff.Memory != 128 {
+		t.Fatalf("expected no overlay for the dev context, got memory %d", ff.Memory)
+	}
+
+	viper.Set(config.CurrentContext, "prod")
+	ff, err = ParseFuncFileV20180708(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ff.Memory != 1024 {
+		t.Fatalf("expected overlay memory 1024, got %d", ff.Memory)
+	}
+	expected := map[string]string{"A": "base", "B": "prod", "C": "prod"}
+	if !reflect.DeepEqual(ff.Config, expected) {
+		t.Fatalf("expected merged config %v, got %v", expected, ff.Config)
+	}
+
+	if _, err := BumpItV20180708(fpath, Patch); err != nil {
+		t.Fatal(err)
+	}
+	base, err := parseFuncFileV20180708(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base.Version != "0.0.2" || base.Memory != 128 || len(base.Config) != 2 {
+		t.Fatalf("expected bump to only change the version of the base func file, got %+v", base)
+	}
+}
+
+func TestVersionFuncFileOverlay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "overlay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, dir, "func.yaml", "schema_version: 20180708\nname: fn\nmemory: 128\n")
+	writeTestFile(t, dir, "func.prod.yaml", "memory: 1024\n")
+	fpath := filepath.Join(dir, "func.yaml")
+
+	defer viper.Set(config.CurrentContext, viper.GetString(config.CurrentContext))
+	viper.Set(config.CurrentContext, "prod")
+	ff, err := ParseFuncFileV20180708(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := versionFuncFileV20180708(ioutil.Discard, fpath, ff); err != nil {
+		t.Fatal(err)
+	}
+	if ff.Version != InitialVersion || ff.Memory != 1024 {
+		t.Fatalf("expected the initial version with the overlay kept, got %+v", ff)
+	}
+	base, err := parseFuncFileV20180708(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base.Version != InitialVersion || base.Memory != 128 {
+		t.Fatalf("expected only the version to be stored in the base func file, got %+v", base)
+	}
+}
@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fnproject/cli/config"
+	"github.com/fnproject/cli/langs"
+)
+
+// Software bill of materials formats
+const (
+	SBOMFormatSPDX      = "spdx"
+	SBOMFormatCycloneDX = "cyclonedx"
+)
+
+var sbomExtensions = map[string]string{
+	SBOMFormatSPDX:      ".spdx.json",
+	SBOMFormatCycloneDX: ".cdx.json",
+}
+
+// CheckSBOMFormat returns an error if format isn't a software bill of materials format
+func CheckSBOMFormat(format string) error {
+	if _, ok := sbomExtensions[format]; !ok {
+		return fmt.Errorf("unknown SBOM format %s, must be %s or %s", format, SBOMFormatSPDX, SBOMFormatCycloneDX)
+	}
+	return nil
+}
+
+// SBOMPath returns the path of the software bill of materials in format of the function in dir
+func SBOMPath(dir, format string) string {
+	return filepath.Join(dir, LocalStateDirName, "sbom"+sbomExtensions[format])
+}
+
+// ArchiveSBOMPath returns the path of the software bill of materials in format that goes with the image
+// archive at path, next to its metadata file
+func ArchiveSBOMPath(path, format string) string {
+	return path + sbomExtensions[format]
+}
+
+// WriteSBOMV20180708 writes the software bill of materials in format of the function in dir to path, listing
+// the dependencies its language helper finds in its manifests
+func WriteSBOMV20180708(dir string, ff *FuncFileV20180708, format, path string) error {
+	if err := CheckSBOMFormat(format); err != nil {
+		return err
+	}
+	if ff.Runtime == FuncfileDockerRuntime {
+		return fmt.Errorf("cannot generate an SBOM for the %s runtime, its dependencies are only known to its Dockerfile", FuncfileDockerRuntime)
+	}
+	helper := langs.GetLangHelper(ff.Runtime)
+	if helper == nil {
+		return fmt.Errorf("Cannot generate an SBOM, no language helper found for %v", ff.Runtime)
+	}
+	deps, err := helper.Dependencies(dir)
+	if err != nil {
+		return fmt.Errorf("error reading the dependencies of %s: %v", ff.Name, err)
+	}
+
+	var doc interface{}
+	if format == SBOMFormatSPDX {
+		doc = spdxDocument(ff, deps)
+	} else {
+		doc = cycloneDXDocument(ff, deps)
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// sbomID returns an ID that is the same for every SBOM of the same function version and dependencies
+func sbomID(ff *FuncFileV20180708, deps []langs.Dependency) [sha256.Size]byte {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", ff.Name, ff.Version, ff.ImageNameV20180708())
+	for _, d := range deps {
+		fmt.Fprintln(h, d.PURL)
+	}
+	var id [sha256.Size]byte
+	copy(id[:], h.Sum(nil))
+	return id
+}
+
+func sbomCreated() string {
+	return buildTime().UTC().Format(time.RFC3339)
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxPackage struct {
+	Name                  string            `json:"name"`
+	SPDXID                string            `json:"SPDXID"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxDocument returns an SPDX 2.3 document, see https://spdx.github.io/spdx-spec/v2.3/
+func spdxDocument(ff *FuncFileV20180708, deps []langs.Dependency) interface{} {
+	const fnID = "SPDXRef-Function"
+	packages := []spdxPackage{{
+		Name:                  ff.Name,
+		SPDXID:                fnID,
+		VersionInfo:           ff.Version,
+		DownloadLocation:      "NOASSERTION",
+		PrimaryPackagePurpose: "CONTAINER",
+	}}
+	relationships := []spdxRelationship{{"SPDXRef-DOCUMENT", "DESCRIBES", fnID}}
+	for i, d := range deps {
+		id := fmt.Sprintf("SPDXRef-Package-%d", i+1)
+		packages = append(packages, spdxPackage{
+			Name:             d.Name,
+			SPDXID:           id,
+			VersionInfo:      d.Version,
+			DownloadLocation: "NOASSERTION",
+			ExternalRefs:     []spdxExternalRef{{"PACKAGE-MANAGER", "purl", d.PURL}},
+		})
+		relationships = append(relationships, spdxRelationship{fnID, "DEPENDS_ON", id})
+	}
+	return map[string]interface{}{
+		"spdxVersion":       "SPDX-2.3",
+		"dataLicense":       "CC0-1.0",
+		"SPDXID":            "SPDXRef-DOCUMENT",
+		"name":              ff.ImageNameV20180708(),
+		"documentNamespace": fmt.Sprintf("https://fnproject.io/spdx/%s-%s-%x", ff.Name, ff.Version, sbomID(ff, deps)),
+		"creationInfo": map[string]interface{}{
+			"created":  sbomCreated(),
+			"creators": []string{"Tool: fn-" + config.Version},
+		},
+		"packages":      packages,
+		"relationships": relationships,
+	}
+}
+
+type cycloneDXComponent struct {
+	Type    string `json:"type"`
+	BOMRef  string `json:"bom-ref,omitempty"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	PURL    string `json:"purl,omitempty"`
+}
+
+// cycloneDXDocument returns a CycloneDX 1.4 document, see https://cyclonedx.org/docs/1.4/json/
+func cycloneDXDocument(ff *FuncFileV20180708, deps []langs.Dependency) interface{} {
+	id := sbomID(ff, deps)
+	// a version 4 UUID layout, from the ID rather than random bytes
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	components := []cycloneDXComponent{}
+	for _, d := range deps {
+		components = append(components, cycloneDXComponent{
+			Type:    "library",
+			BOMRef:  d.PURL,
+			Name:    d.Name,
+			Version: d.Version,
+			PURL:    d.PURL,
+		})
+	}
+	return map[string]interface{}{
+		"bomFormat":    "CycloneDX",
+		"specVersion":  "1.4",
+		"serialNumber": fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16]),
+		"version":      1,
+		"metadata": map[string]interface{}{
+			"timestamp": sbomCreated(),
+			"tools":     []map[string]string{{"vendor": "fnproject", "name": "fn", "version": config.Version}},
+			"component": cycloneDXComponent{Type: "container", Name: ff.Name, Version: ff.Version},
+		},
+		"components": components,
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestWriteSBOMV20180708(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sbom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestFile(t, dir, "requirements.txt", "fdk==0.1.18\n")
+	ff := &FuncFileV20180708{Name: "hello", Version: "0.0.2", Runtime: "python"}
+
+	read := func(path string) map[string]interface{} {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(b, &doc); err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+
+	path := SBOMPath(dir, SBOMFormatSPDX)
+	if err := WriteSBOMV20180708(dir, ff, SBOMFormatSPDX, path); err != nil {
+		t.Fatal(err)
+	}
+	doc := read(path)
+	if doc["spdxVersion"] != "SPDX-2.3" {
+		t.Errorf("expected an SPDX 2.3 document, got %v", doc["spdxVersion"])
+	}
+	packages := doc["packages"].([]interface{})
+	if len(packages) != 2 {
+		t.Fatalf("expected the function and its dependency, got %v", packages)
+	}
+	if name := packages[1].(map[string]interface{})["name"]; name != "fdk" {
+		t.Errorf("expected the fdk dependency, got %v", name)
+	}
+
+	path = SBOMPath(dir, SBOMFormatCycloneDX)
+	if err := WriteSBOMV20180708(dir, ff, SBOMFormatCycloneDX, path); err != nil {
+		t.Fatal(err)
+	}
+	doc = read(path)
+	components := doc["components"].([]interface{})
+	if len(components) != 1 || components[0].(map[string]interface{})["purl"] != "pkg:pypi/fdk@0.1.18" {
+		t.Errorf("expected the fdk component, got %v", components)
+	}
+
+	if err := WriteSBOMV20180708(dir, ff, "swid", path); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	ff.Runtime = FuncfileDockerRuntime
+	if err := WriteSBOMV20180708(dir, ff, SBOMFormatSPDX, path); err == nil {
+		t.Error("expected an error for the docker runtime")
+	}
+}
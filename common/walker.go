@@ -17,7 +17,6 @@
 package common
 
 import (
-	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -44,10 +43,6 @@ func WalkFuncs(root string, walkFn walkFuncsFunc) error {
 			return nil
 		}
 
-		// TODO: test/try this again to speed up deploys.
-		if false && !isstale(path) {
-			return nil
-		}
 		// Then we found a func file, so let's deploy it:
 		ff, err := ParseFuncfile(path)
 		// if err != nil {
@@ -74,10 +69,6 @@ func WalkFuncsV20180708(root string, walkFn walkFuncsFuncV20180708) error {
 			return nil
 		}
 
-		// TODO: test/try this again to speed up deploys.
-		if false && !isstale(path) {
-			return nil
-		}
 		// Then we found a func file, so let's deploy it:
 		ff, err := ParseFuncFileV20180708(path)
 		// if err != nil {
@@ -86,32 +77,3 @@ func WalkFuncsV20180708(root string, walkFn walkFuncsFuncV20180708) error {
 		return walkFn(path, ff, err)
 	})
 }
-
-// Theory of operation: this takes an optimistic approach to detect whether a
-// package must be rebuild/bump/deployed. It loads for all files mtime's and
-// compare with functions.json own mtime. If any file is younger than
-// functions.json, it triggers a rebuild.
-// The problem with this approach is that depending on the OS running it, the
-// time granularity of these timestamps might lead to false negatives - that is
-// a package that is stale but it is not recompiled. A more elegant solution
-// could be applied here, like https://golang.org/src/cmd/go/pkg.go#L1111
-func isstale(path string) bool {
-	fi, err := os.Stat(path)
-	if err != nil {
-		return true
-	}
-
-	fnmtime := fi.ModTime()
-	dir := filepath.Dir(path)
-	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if info.IsDir() {
-			return nil
-		}
-		if info.ModTime().After(fnmtime) {
-			return errors.New("found stale package")
-		}
-		return nil
-	})
-
-	return err != nil
-}
@@ -34,6 +34,7 @@ const (
 	rootConfigPathName = ".fn"
 
 	contextsPathName                       = "contexts"
+	buildLogsPathName                      = "build-logs"
 	configName                             = "config"
 	contextConfigFileName                  = "config.yaml"
 	defaultContextFileName                 = "default.yaml"
@@ -50,6 +51,11 @@ const (
 	EnvFnRegistry = "registry"
 	EnvFnContext  = "context"
 
+	// PinImageDigest makes deploy update functions with the digest of the pushed image rather than its tag
+	PinImageDigest = "pin-digest"
+	// ContainerEngine is the CLI used to build, push and run images, eg: docker or podman
+	ContainerEngine = "container-engine"
+
 	OCI_CLI_AUTH_ENV_VAR            = "OCI_CLI_AUTH"
 	OCI_CLI_AUTH_INSTANCE_PRINCIPAL = "instance_principal"
 	OCI_CLI_AUTH_INSTANCE_OBO_USER  = "instance_obo_user"
@@ -203,6 +209,11 @@ func ensureConfiguration() error {
 	return nil
 }
 
+// GetBuildLogsPath : Returns the path to the directory build logs are kept in.
+func GetBuildLogsPath() string {
+	return filepath.Join(GetHomeDir(), rootConfigPathName, buildLogsPathName)
+}
+
 // GetContextsPath : Returns the path to the contexts directory.
 func GetContextsPath() string {
 	contextsPath := filepath.Join(rootConfigPathName, contextsPathName)
@@ -99,10 +99,22 @@ type LangHelper interface {
 	RunFromImage() (string, error)
 	// If set to false, it will use a single Docker build step, rather than multi-stage
 	IsMultiStage() bool
-	// Dockerfile build lines for building dependencies or anything else language specific
-	DockerfileBuildCmds() []string
+	// Dockerfile build lines for building dependencies or anything else language specific, dir is the function directory
+	DockerfileBuildCmds(dir string) []string
 	// DockerfileCopyCmds will run in second/final stage of multi-stage build to copy artifacts form the build stage
-	DockerfileCopyCmds() []string
+	DockerfileCopyCmds(dir string) []string
+	// CacheDirs are directories of the build image that dependencies are downloaded to, mounted as caches on
+	// the RUN steps of DockerfileBuildCmds when the build supports BuildKit cache mounts
+	CacheDirs() []string
+	// SecretMounts maps the ids of build secrets the RUN steps of DockerfileBuildCmds can use, eg: credentials for
+	// a private package registry, to the path they're mounted at
+	SecretMounts() map[string]string
+	// DockerIgnore lists .dockerignore patterns of files in the function directory dir that the build doesn't
+	// need, eg: local build output, so they aren't sent to the container engine or copied into the image
+	DockerIgnore(dir string) []string
+	// Dependencies reads the packages the function in dir depends on from the manifests of its language, for
+	// its software bill of materials
+	Dependencies(dir string) ([]Dependency, error)
 	// Entrypoint sets the Docker Entrypoint. One of Entrypoint or Cmd is required.
 	Entrypoint() (string, error)
 	// Cmd sets the Docker command. One of Entrypoint or Cmd is required.
@@ -110,8 +122,9 @@ type LangHelper interface {
 	// CustomMemory allows a helper to specify a base memory amount, return "" to leave unspecified and let the runtime decide.
 	CustomMemory() uint64
 	HasPreBuild() bool
-	PreBuild() error
-	AfterBuild() error
+	// PreBuild and AfterBuild run before and after the image build of the function in dir
+	PreBuild(dir string) error
+	AfterBuild(dir string) error
 	// HasBoilerplate indicates whether a language has support for generating function boilerplate.
 	HasBoilerplate() bool
 	// GenerateBoilerplate generates basic function boilerplate. Returns ErrBoilerplateExists if the function file
@@ -121,6 +134,8 @@ type LangHelper interface {
 	FixImagesOnInit() bool
 	// GetLatestFDKVersion checks the package repository and returns the latest version of FDK version if available.
 	GetLatestFDKVersion() (string, error)
+	// Platforms lists the platforms, eg: linux/amd64, that the build and run images are published for
+	Platforms() []string
 }
 
 func defaultHandles(h LangHelper, lang string) bool {
@@ -132,23 +147,35 @@ func defaultHandles(h LangHelper, lang string) bool {
 	return false
 }
 
+// CachedBuilder is implemented by helpers whose build stage is different when their CacheDirs are mounted, eg:
+// to download dependencies to the mounted directories rather than to where the build image keeps its own.
+type CachedBuilder interface {
+	// DockerfileCachedBuildCmds are used instead of DockerfileBuildCmds when CacheDirs are mounted
+	DockerfileCachedBuildCmds(dir string) []string
+}
+
 // BaseHelper is empty implementation of LangHelper for embedding in implementations.
 type BaseHelper struct {
 }
 
-func (h *BaseHelper) IsMultiStage() bool                   { return true }
-func (h *BaseHelper) DockerfileBuildCmds() []string        { return []string{} }
-func (h *BaseHelper) DockerfileCopyCmds() []string         { return []string{} }
-func (h *BaseHelper) Entrypoint() (string, error)          { return "", nil }
-func (h *BaseHelper) Cmd() (string, error)                 { return "", nil }
-func (h *BaseHelper) HasPreBuild() bool                    { return false }
-func (h *BaseHelper) PreBuild() error                      { return nil }
-func (h *BaseHelper) AfterBuild() error                    { return nil }
-func (h *BaseHelper) HasBoilerplate() bool                 { return false }
-func (h *BaseHelper) GenerateBoilerplate(string) error     { return nil }
-func (h *BaseHelper) CustomMemory() uint64                 { return 0 }
-func (h *BaseHelper) FixImagesOnInit() bool                { return false }
-func (h *BaseHelper) GetLatestFDKVersion() (string, error) { return "", nil }
+func (h *BaseHelper) IsMultiStage() bool                        { return true }
+func (h *BaseHelper) DockerfileBuildCmds(string) []string       { return []string{} }
+func (h *BaseHelper) DockerfileCopyCmds(string) []string        { return []string{} }
+func (h *BaseHelper) CacheDirs() []string                       { return nil }
+func (h *BaseHelper) SecretMounts() map[string]string           { return nil }
+func (h *BaseHelper) DockerIgnore(string) []string              { return nil }
+func (h *BaseHelper) Dependencies(string) ([]Dependency, error) { return nil, nil }
+func (h *BaseHelper) Entrypoint() (string, error)               { return "", nil }
+func (h *BaseHelper) Cmd() (string, error)                      { return "", nil }
+func (h *BaseHelper) HasPreBuild() bool                         { return false }
+func (h *BaseHelper) PreBuild(string) error                     { return nil }
+func (h *BaseHelper) AfterBuild(string) error                   { return nil }
+func (h *BaseHelper) HasBoilerplate() bool                      { return false }
+func (h *BaseHelper) GenerateBoilerplate(string) error          { return nil }
+func (h *BaseHelper) CustomMemory() uint64                      { return 0 }
+func (h *BaseHelper) FixImagesOnInit() bool                     { return false }
+func (h *BaseHelper) GetLatestFDKVersion() (string, error)      { return "", nil }
+func (h *BaseHelper) Platforms() []string                       { return []string{"linux/amd64"} }
 
 // exists checks if a file exists
 func exists(name string) bool {
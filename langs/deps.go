@@ -0,0 +1,365 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langs
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// an exact npm version, ranges like ^1.0.0 aren't versions
+	npmVersion = regexp.MustCompile(`^\d+\.\d+\.\d+`)
+	// eg: ${fdk.version}
+	mavenProperty = regexp.MustCompile(`\$\{([^}]+)\}`)
+	pipName       = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*`)
+	pipNameSep    = regexp.MustCompile(`[-_.]+`)
+	// gems are listed under the specs of the GEM section with four spaces, eg: "    json (2.3.0)", and
+	// their own dependencies with six
+	gemSpec = regexp.MustCompile(`^    ([^ ]+) \(([^)]+)\)$`)
+)
+
+// Dependency is a package a function depends on, as listed in the manifests of its language
+type Dependency struct {
+	Name    string
+	Version string
+	// PURL is the package URL of the dependency, see https://github.com/package-url/purl-spec
+	PURL string
+}
+
+func newDependency(purlType, namespace, name, version string) Dependency {
+	purl := "pkg:" + purlType + "/"
+	if namespace != "" {
+		purl += purlEscape(namespace) + "/"
+	}
+	purl += purlEscape(name)
+	if version != "" {
+		purl += "@" + purlEscape(version)
+	}
+	fullName := name
+	if namespace != "" {
+		sep := "/"
+		if purlType == "maven" {
+			sep = ":"
+		}
+		fullName = namespace + sep + name
+	}
+	return Dependency{Name: fullName, Version: version, PURL: purl}
+}
+
+// purlEscape percent-encodes each segment of a package URL path, including the @ of npm scopes
+func purlEscape(s string) string {
+	segments := strings.Split(s, "/")
+	for i, seg := range segments {
+		segments[i] = strings.Replace(url.PathEscape(seg), "@", "%40", -1)
+	}
+	return strings.Join(segments, "/")
+}
+
+// goDependency splits the module path of a go dependency into the namespace and name of its package URL
+func goDependency(module, version string) Dependency {
+	if i := strings.LastIndex(module, "/"); i >= 0 {
+		return newDependency("golang", module[:i], module[i+1:], version)
+	}
+	return newDependency("golang", "", module, version)
+}
+
+// sortDependencies sorts deps by name and version and removes duplicates
+func sortDependencies(deps []Dependency) []Dependency {
+	sort.Slice(deps, func(i, j int) bool {
+		if deps[i].Name != deps[j].Name {
+			return deps[i].Name < deps[j].Name
+		}
+		return deps[i].Version < deps[j].Version
+	})
+	var r []Dependency
+	for i, d := range deps {
+		if i > 0 && d == deps[i-1] {
+			continue
+		}
+		r = append(r, d)
+	}
+	return r
+}
+
+// readLines returns the lines of the file at path, or nil if there isn't one
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	return lines, nil
+}
+
+// goModDependencies reads the required modules from the go.mod in dir, or every module in go.sum if there
+// is no go.mod
+func goModDependencies(dir string) ([]Dependency, error) {
+	lines, err := readLines(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	var deps []Dependency
+	if lines != nil {
+		inRequire := false
+		for _, l := range lines {
+			if i := strings.Index(l, "//"); i >= 0 {
+				l = l[:i]
+			}
+			fields := strings.Fields(l)
+			switch {
+			case len(fields) == 0:
+			case inRequire && fields[0] == ")":
+				inRequire = false
+			case fields[0] == "require" && len(fields) == 2 && fields[1] == "(":
+				inRequire = true
+			case fields[0] == "require" && len(fields) == 3:
+				deps = append(deps, goDependency(fields[1], fields[2]))
+			case inRequire && len(fields) == 2:
+				deps = append(deps, goDependency(fields[0], fields[1]))
+			}
+		}
+		return sortDependencies(deps), nil
+	}
+
+	lines, err = readLines(filepath.Join(dir, "go.sum"))
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range lines {
+		// eg: github.com/fnproject/fdk-go v0.0.2 h1:..., with a /go.mod version for each module in the graph
+		fields := strings.Fields(l)
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		deps = append(deps, goDependency(fields[0], fields[1]))
+	}
+	return sortDependencies(deps), nil
+}
+
+// npmDependencies reads the installed packages from the package-lock.json in dir, or the dependencies of
+// package.json if there is no lock file
+func npmDependencies(dir string) ([]Dependency, error) {
+	var deps []Dependency
+	b, err := ioutil.ReadFile(filepath.Join(dir, "package-lock.json"))
+	if err == nil {
+		var lock struct {
+			// lockfileVersion 2 and later, keyed by path, eg: node_modules/@fnproject/fdk
+			Packages map[string]struct {
+				Version string `json:"version"`
+				Dev     bool   `json:"dev"`
+			} `json:"packages"`
+			// lockfileVersion 1
+			Dependencies map[string]npmLockDependency `json:"dependencies"`
+		}
+		if err := json.Unmarshal(b, &lock); err != nil {
+			return nil, fmt.Errorf("error parsing package-lock.json: %v", err)
+		}
+		if len(lock.Packages) > 0 {
+			for path, p := range lock.Packages {
+				i := strings.LastIndex(path, "node_modules/")
+				if i < 0 || p.Dev {
+					continue
+				}
+				deps = append(deps, npmDependency(path[i+len("node_modules/"):], p.Version))
+			}
+		} else {
+			deps = appendNpmLockDependencies(deps, lock.Dependencies)
+		}
+		return sortDependencies(deps), nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	b, err = ioutil.ReadFile(filepath.Join(dir, "package.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pkg struct {
+		Dependencies map[string]string `json:"dependencies"`
+	}
+	if err := json.Unmarshal(b, &pkg); err != nil {
+		return nil, fmt.Errorf("error parsing package.json: %v", err)
+	}
+	for name, version := range pkg.Dependencies {
+		// only exact versions are versions, ranges like ^1.0.0 are left out
+		if !npmVersion.MatchString(version) {
+			version = ""
+		}
+		deps = append(deps, npmDependency(name, version))
+	}
+	return sortDependencies(deps), nil
+}
+
+type npmLockDependency struct {
+	Version      string                       `json:"version"`
+	Dev          bool                         `json:"dev"`
+	Dependencies map[string]npmLockDependency `json:"dependencies"`
+}
+
+func appendNpmLockDependencies(deps []Dependency, lockDeps map[string]npmLockDependency) []Dependency {
+	for name, d := range lockDeps {
+		if d.Dev {
+			continue
+		}
+		deps = append(deps, npmDependency(name, d.Version))
+		deps = appendNpmLockDependencies(deps, d.Dependencies)
+	}
+	return deps
+}
+
+func npmDependency(name, version string) Dependency {
+	// scoped packages, eg: @fnproject/fdk
+	if strings.HasPrefix(name, "@") {
+		if i := strings.Index(name, "/"); i > 0 {
+			return newDependency("npm", name[:i], name[i+1:], version)
+		}
+	}
+	return newDependency("npm", "", name, version)
+}
+
+// mavenDependencies reads the dependencies of the pom.xml in dir, with ${property} versions resolved from
+// the properties of the pom. Only direct dependencies are listed, as resolving their transitive dependencies
+// needs maven. Test and provided dependencies are left out as they aren't packaged into the image.
+func mavenDependencies(dir string) ([]Dependency, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, "pom.xml"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pom struct {
+		Version    string `xml:"version"`
+		Properties struct {
+			Entries []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:"properties"`
+		Dependencies []struct {
+			GroupID    string `xml:"groupId"`
+			ArtifactID string `xml:"artifactId"`
+			Version    string `xml:"version"`
+			Scope      string `xml:"scope"`
+		} `xml:"dependencies>dependency"`
+	}
+	if err := xml.Unmarshal(b, &pom); err != nil {
+		return nil, fmt.Errorf("error parsing pom.xml: %v", err)
+	}
+	props := map[string]string{"project.version": pom.Version}
+	for _, p := range pom.Properties.Entries {
+		props[p.XMLName.Local] = strings.TrimSpace(p.Value)
+	}
+	resolve := func(s string) string {
+		return mavenProperty.ReplaceAllStringFunc(strings.TrimSpace(s), func(ref string) string {
+			if v, ok := props[ref[2:len(ref)-1]]; ok {
+				return v
+			}
+			return ref
+		})
+	}
+	var deps []Dependency
+	for _, d := range pom.Dependencies {
+		if d.Scope == "test" || d.Scope == "provided" {
+			continue
+		}
+		deps = append(deps, newDependency("maven", resolve(d.GroupID), resolve(d.ArtifactID), resolve(d.Version)))
+	}
+	return sortDependencies(deps), nil
+}
+
+// pipDependencies reads the requirements.txt in dir. Only requirements pinned with == have a version.
+func pipDependencies(dir string) ([]Dependency, error) {
+	lines, err := readLines(filepath.Join(dir, "requirements.txt"))
+	if err != nil {
+		return nil, err
+	}
+	var deps []Dependency
+	for _, l := range lines {
+		if i := strings.Index(l, "#"); i >= 0 {
+			l = l[:i]
+		}
+		l = strings.TrimSpace(l)
+		// options like -r other.txt or --index-url, and environment markers
+		if l == "" || strings.HasPrefix(l, "-") {
+			continue
+		}
+		if i := strings.Index(l, ";"); i >= 0 {
+			l = strings.TrimSpace(l[:i])
+		}
+		name := pipName.FindString(l)
+		if name == "" {
+			continue
+		}
+		version := ""
+		if rest := strings.TrimSpace(l[len(name):]); strings.HasPrefix(rest, "==") && !strings.Contains(rest, ",") {
+			version = strings.TrimSpace(rest[2:])
+		}
+		// pypi names are case insensitive and treat - _ and . alike
+		name = strings.ToLower(pipNameSep.ReplaceAllString(name, "-"))
+		deps = append(deps, newDependency("pypi", "", name, version))
+	}
+	return sortDependencies(deps), nil
+}
+
+// gemDependencies reads the installed gems from the Gemfile.lock in dir
+func gemDependencies(dir string) ([]Dependency, error) {
+	lines, err := readLines(filepath.Join(dir, "Gemfile.lock"))
+	if err != nil {
+		return nil, err
+	}
+	var deps []Dependency
+	inGems := false
+	for _, l := range lines {
+		if l != "" && !strings.HasPrefix(l, " ") {
+			inGems = l == "GEM"
+			continue
+		}
+		if !inGems {
+			continue
+		}
+		if m := gemSpec.FindStringSubmatch(l); m != nil {
+			deps = append(deps, newDependency("gem", "", m[1], m[2]))
+		}
+	}
+	return sortDependencies(deps), nil
+}
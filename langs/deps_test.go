@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2019, 2020 Oracle and/or its affiliates. All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDependencies(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		files    map[string]string
+		parse    func(string) ([]Dependency, error)
+		expected []Dependency
+	}{
+		{
+			name: "go.mod",
+			files: map[string]string{"go.mod": `module func
+
+go 1.15
+
+require github.com/fnproject/fdk-go v0.0.2
+
+require (
+	golang.org/x/net v0.0.1 // indirect
+)
+`},
+			parse: goModDependencies,
+			expected: []Dependency{
+				{"github.com/fnproject/fdk-go", "v0.0.2", "pkg:golang/github.com/fnproject/fdk-go@v0.0.2"},
+				{"golang.org/x/net", "v0.0.1", "pkg:golang/golang.org/x/net@v0.0.1"},
+			},
+		},
+		{
+			name: "go.sum without go.mod",
+			files: map[string]string{"go.sum": `github.com/fnproject/fdk-go v0.0.2 h1:abc=
+github.com/fnproject/fdk-go v0.0.2/go.mod h1:def=
+`},
+			parse: goModDependencies,
+			expected: []Dependency{
+				{"github.com/fnproject/fdk-go", "v0.0.2", "pkg:golang/github.com/fnproject/fdk-go@v0.0.2"},
+			},
+		},
+		{
+			name: "package-lock.json",
+			files: map[string]string{"package-lock.json": `{"lockfileVersion": 2, "packages": {
+	"": {"name": "hello"},
+	"node_modules/@fnproject/fdk": {"version": "0.0.20"},
+	"node_modules/mocha": {"version": "8.0.0", "dev": true}
+}}`},
+			parse: npmDependencies,
+			expected: []Dependency{
+				{"@fnproject/fdk", "0.0.20", "pkg:npm/%40fnproject/fdk@0.0.20"},
+			},
+		},
+		{
+			name:  "package.json without a lock file",
+			files: map[string]string{"package.json": `{"dependencies": {"@fnproject/fdk": ">=0.0.20", "left-pad": "1.3.0"}}`},
+			parse: npmDependencies,
+			expected: []Dependency{
+				{"@fnproject/fdk", "", "pkg:npm/%40fnproject/fdk"},
+				{"left-pad", "1.3.0", "pkg:npm/left-pad@1.3.0"},
+			},
+		},
+		{
+			name: "pom.xml",
+			files: map[string]string{"pom.xml": `<project>
+	<version>1.0.0</version>
+	<properties><fdk.version>1.0.105</fdk.version></properties>
+	<dependencies>
+		<dependency><groupId>com.fnproject.fn</groupId><artifactId>api</artifactId><version>${fdk.version}</version></dependency>
+		<dependency><groupId>junit</groupId><artifactId>junit</artifactId><version>4.12</version><scope>test</scope></dependency>
+		<dependency><groupId>javax.servlet</groupId><artifactId>servlet-api</artifactId><version>2.5</version><scope>provided</scope></dependency>
+	</dependencies>
+</project>`},
+			parse: mavenDependencies,
+			expected: []Dependency{
+				{"com.fnproject.fn:api", "1.0.105", "pkg:maven/com.fnproject.fn/api@1.0.105"},
+			},
+		},
+		{
+			name: "requirements.txt",
+			files: map[string]string{"requirements.txt": `# the fdk
+fdk==0.1.18
+-r other.txt
+Flask_Cors>=3.0 ; python_version >= "3"
+`},
+			parse: pipDependencies,
+			expected: []Dependency{
+				{"fdk", "0.1.18", "pkg:pypi/fdk@0.1.18"},
+				{"flask-cors", "", "pkg:pypi/flask-cors"},
+			},
+		},
+		{
+			name: "Gemfile.lock",
+			files: map[string]string{"Gemfile.lock": `GEM
+  remote: https://rubygems.org/
+  specs:
+    fdk (0.0.20)
+      json (>= 2.1)
+    json (2.3.0)
+
+PLATFORMS
+  ruby
+`},
+			parse: gemDependencies,
+			expected: []Dependency{
+				{"fdk", "0.0.20", "pkg:gem/fdk@0.0.20"},
+				{"json", "2.3.0", "pkg:gem/json@2.3.0"},
+			},
+		},
+		{
+			name:  "no manifest",
+			parse: pipDependencies,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "deps")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			for name, content := range tc.files {
+				if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			deps, err := tc.parse(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(deps, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, deps)
+			}
+		})
+	}
+}
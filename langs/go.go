@@ -57,15 +57,15 @@ func (lh *GoLangHelper) RunFromImage() (string, error) {
 	return fmt.Sprintf("fnproject/go:%s", lh.Version), nil
 }
 
-func (h *GoLangHelper) DockerfileBuildCmds() []string {
+func (h *GoLangHelper) DockerfileBuildCmds(dir string) []string {
 	r := []string{}
 	// more info on Go multi-stage builds: https://medium.com/travis-on-docker/multi-stage-docker-builds-for-creating-tiny-go-images-e0e1867efe5a
 	// TODO: if we keep the go.sum on user's drive, we can put this after the dep commands and then the dep layers will be cached.
-	vendor := exists("vendor/")
+	vendor := exists(filepath.Join(dir, "vendor"))
 	// skip dep tool install if vendor is there
-	if !vendor && exists("Gopkg.toml") {
+	if !vendor && exists(filepath.Join(dir, "Gopkg.toml")) {
 		r = append(r, "RUN go get -u github.com/golang/dep/cmd/dep")
-		if exists("Gopkg.lock") {
+		if exists(filepath.Join(dir, "Gopkg.lock")) {
 			r = append(r, "ADD Gopkg.* /go/src/func/")
 			r = append(r, "RUN cd /go/src/func/ && dep ensure --vendor-only")
 			r = append(r, "ADD . /go/src/func/")
@@ -73,7 +73,7 @@ func (h *GoLangHelper) DockerfileBuildCmds() []string {
 			r = append(r, "ADD . /go/src/func/")
 			r = append(r, "RUN cd /go/src/func/ && dep ensure")
 		}
-	} else if exists("go.mod") {
+	} else if exists(filepath.Join(dir, "go.mod")) {
 		r = append(r, "WORKDIR /go/src/func/")
 		r = append(r, "ENV GO111MODULE=on")
 		if vendor {
@@ -89,7 +89,17 @@ func (h *GoLangHelper) DockerfileBuildCmds() []string {
 	return r
 }
 
-func (h *GoLangHelper) DockerfileCopyCmds() []string {
+// CacheDirs - the module cache and build cache
+func (h *GoLangHelper) CacheDirs() []string {
+	return []string{"/go/pkg/mod", "/root/.cache/go-build"}
+}
+
+// Dependencies - the modules required by go.mod
+func (h *GoLangHelper) Dependencies(dir string) ([]Dependency, error) {
+	return goModDependencies(dir)
+}
+
+func (h *GoLangHelper) DockerfileCopyCmds(dir string) []string {
 	return []string{
 		"COPY --from=build-stage /go/src/func/func /function/",
 	}
@@ -133,16 +133,26 @@ func (h *JavaLangHelper) Cmd() (string, error) {
 }
 
 // DockerfileCopyCmds returns the Docker COPY command to copy the compiled Java function jar and dependencies.
-func (h *JavaLangHelper) DockerfileCopyCmds() []string {
+func (h *JavaLangHelper) DockerfileCopyCmds(dir string) []string {
 	return []string{
 		"COPY --from=build-stage /function/target/*.jar /function/app/",
 	}
 }
 
 // DockerfileBuildCmds returns the build stage steps to compile the Maven function project.
-func (h *JavaLangHelper) DockerfileBuildCmds() []string {
+func (h *JavaLangHelper) DockerfileBuildCmds(dir string) []string {
+	return javaBuildCmds(mavenOpts(mavenImageRepository))
+}
+
+// DockerfileCachedBuildCmds compiles the Maven function project with the mounted Maven repository of CacheDirs,
+// which is given the dependencies the build image has first.
+func (h *JavaLangHelper) DockerfileCachedBuildCmds(dir string) []string {
+	return append([]string{seedMavenCacheRepository}, javaBuildCmds(mavenOpts(mavenCacheRepository))...)
+}
+
+func javaBuildCmds(mavenOpts string) []string {
 	return []string{
-		fmt.Sprintf("ENV MAVEN_OPTS %s", mavenOpts()),
+		fmt.Sprintf("ENV MAVEN_OPTS %s", mavenOpts),
 		"ADD pom.xml /function/pom.xml",
 		"RUN [\"mvn\", \"package\", \"dependency:copy-dependencies\", \"-DincludeScope=runtime\", " +
 			"\"-DskipTests=true\", \"-Dmdep.prependGroupId=true\", \"-DoutputDirectory=target\", \"--fail-never\"]",
@@ -151,24 +161,50 @@ func (h *JavaLangHelper) DockerfileBuildCmds() []string {
 	}
 }
 
+// CacheDirs returns the local Maven repository of DockerfileCachedBuildCmds.
+func (h *JavaLangHelper) CacheDirs() []string {
+	return []string{mavenCacheRepository}
+}
+
+// SecretMounts mounts a maven-settings secret as the Maven user settings, eg: for the credentials of a private repository.
+func (h *JavaLangHelper) SecretMounts() map[string]string {
+	return map[string]string{"maven-settings": "/root/.m2/settings.xml"}
+}
+
+// DockerIgnore ignores the local Maven build output, the image is built from the sources.
+func (h *JavaLangHelper) DockerIgnore(dir string) []string {
+	return []string{"target"}
+}
+
+// Dependencies returns the dependencies of the pom.xml of the function.
+func (h *JavaLangHelper) Dependencies(dir string) ([]Dependency, error) {
+	return mavenDependencies(dir)
+}
+
 // HasPreBuild returns whether the Java Maven runtime has a pre-build step.
 func (h *JavaLangHelper) HasPreBuild() bool { return true }
 
 // PreBuild ensures that the expected the function is based is a maven project.
-func (h *JavaLangHelper) PreBuild() error {
-	wd, err := os.Getwd()
-	if err != nil {
-		return err
-	}
-
-	if !exists(filepath.Join(wd, "pom.xml")) {
+func (h *JavaLangHelper) PreBuild(dir string) error {
+	if !exists(filepath.Join(dir, "pom.xml")) {
 		return errors.New("Could not find pom.xml - are you sure this is a Maven project?")
 	}
 
 	return nil
 }
 
-func mavenOpts() string {
+// Local Maven repositories of builds, the build images come with the dependencies of the FDK in mavenImageRepository
+const (
+	mavenImageRepository = "/usr/share/maven/ref/repository"
+	mavenCacheRepository = "/root/.m2/repository"
+)
+
+// seedMavenCacheRepository copies the dependencies of the build image to the mounted Maven repository, without
+// replacing those already there
+const seedMavenCacheRepository = "RUN cp -R -n " + mavenImageRepository + "/. " + mavenCacheRepository + "/ 2>/dev/null || true"
+
+// mavenOpts returns the MAVEN_OPTS of a build with the local Maven repository repo and the proxies of the environment
+func mavenOpts(repo string) string {
 	var opts bytes.Buffer
 
 	if parsedURL, err := url.Parse(os.Getenv("http_proxy")); err == nil {
@@ -184,7 +220,7 @@ func mavenOpts() string {
 	nonProxyHost := os.Getenv("no_proxy")
 	opts.WriteString(fmt.Sprintf("-Dhttp.nonProxyHosts=%s ", strings.Replace(nonProxyHost, ",", "|", -1)))
 
-	opts.WriteString("-Dmaven.repo.local=/usr/share/maven/ref/repository")
+	opts.WriteString("-Dmaven.repo.local=" + repo)
 
 	return opts.String()
 }
@@ -326,6 +362,14 @@ func (h *JavaLangHelper) FixImagesOnInit() bool {
 	return true
 }
 
+// Platforms - the jdk11 and jre11 fdk images are published for arm64 as well
+func (h *JavaLangHelper) Platforms() []string {
+	if h.version == "11" {
+		return []string{"linux/amd64", "linux/arm64"}
+	}
+	return []string{"linux/amd64"}
+}
+
 const (
 	mavenPomFile = `<?xml version="1.0" encoding="UTF-8"?>
 <project xmlns="http://maven.apache.org/POM/4.0.0"
@@ -119,16 +119,26 @@ func (lh *KotlinLangHelper) Cmd() (string, error) {
 }
 
 // DockerfileCopyCmds returns the Docker COPY command to copy the compiled Kotlin function jar and dependencies.
-func (lh *KotlinLangHelper) DockerfileCopyCmds() []string {
+func (lh *KotlinLangHelper) DockerfileCopyCmds(dir string) []string {
 	return []string{
 		`COPY --from=build-stage /function/target/*.jar /function/app/`,
 	}
 }
 
 // DockerfileBuildCmds returns the build stage steps to compile the Maven function project.
-func (lh *KotlinLangHelper) DockerfileBuildCmds() []string {
+func (lh *KotlinLangHelper) DockerfileBuildCmds(dir string) []string {
+	return kotlinBuildCmds(kotlinMavenOpts(mavenImageRepository))
+}
+
+// DockerfileCachedBuildCmds compiles the Maven function project with the mounted Maven repository of CacheDirs,
+// which is given the dependencies the build image has first.
+func (lh *KotlinLangHelper) DockerfileCachedBuildCmds(dir string) []string {
+	return append([]string{seedMavenCacheRepository}, kotlinBuildCmds(kotlinMavenOpts(mavenCacheRepository))...)
+}
+
+func kotlinBuildCmds(mavenOpts string) []string {
 	return []string{
-		fmt.Sprintf(`ENV MAVEN_OPTS %s`, kotlinMavenOpts()),
+		fmt.Sprintf(`ENV MAVEN_OPTS %s`, mavenOpts),
 		`ADD pom.xml /function/pom.xml`,
 		`RUN ["mvn", "package", "dependency:copy-dependencies", "-DincludeScope=runtime", ` +
 			`"-DskipTests=true", "-Dmdep.prependGroupId=true", "-DoutputDirectory=target", "--fail-never"]`,
@@ -137,24 +147,39 @@ func (lh *KotlinLangHelper) DockerfileBuildCmds() []string {
 	}
 }
 
+// CacheDirs returns the local Maven repository of DockerfileCachedBuildCmds.
+func (lh *KotlinLangHelper) CacheDirs() []string {
+	return []string{mavenCacheRepository}
+}
+
+// SecretMounts mounts a maven-settings secret as the Maven user settings, eg: for the credentials of a private repository.
+func (lh *KotlinLangHelper) SecretMounts() map[string]string {
+	return map[string]string{"maven-settings": "/root/.m2/settings.xml"}
+}
+
+// DockerIgnore ignores the local Maven build output, the image is built from the sources.
+func (lh *KotlinLangHelper) DockerIgnore(dir string) []string {
+	return []string{"target"}
+}
+
+// Dependencies returns the dependencies of the pom.xml of the function.
+func (lh *KotlinLangHelper) Dependencies(dir string) ([]Dependency, error) {
+	return mavenDependencies(dir)
+}
+
 // HasPreBuild returns whether the Java Maven runtime has a pre-build step.
 func (lh *KotlinLangHelper) HasPreBuild() bool { return true }
 
 // PreBuild ensures that the expected the function is based is a maven project.
-func (lh *KotlinLangHelper) PreBuild() error {
-	wd, err := os.Getwd()
-	if err != nil {
-		return err
-	}
-
-	if !exists(filepath.Join(wd, "pom.xml")) {
+func (lh *KotlinLangHelper) PreBuild(dir string) error {
+	if !exists(filepath.Join(dir, "pom.xml")) {
 		return errors.New("Could not find pom.xml - are you sure this is a Maven project?")
 	}
 
 	return nil
 }
 
-func kotlinMavenOpts() string {
+func kotlinMavenOpts(repo string) string {
 	var opts bytes.Buffer
 
 	if parsedURL, err := url.Parse(os.Getenv("http_proxy")); err == nil {
@@ -170,7 +195,7 @@ func kotlinMavenOpts() string {
 	nonProxyHost := os.Getenv("no_proxy")
 	opts.WriteString(fmt.Sprintf("-Dhttp.nonProxyHosts=%s ", strings.Replace(nonProxyHost, ",", "|", -1)))
 
-	opts.WriteString("-Dmaven.repo.local=/usr/share/maven/ref/repository")
+	opts.WriteString("-Dmaven.repo.local=" + repo)
 
 	return opts.String()
 }
@@ -215,6 +240,11 @@ func (lh *KotlinLangHelper) FixImagesOnInit() bool {
 	return true
 }
 
+// Platforms - kotlin builds on the jdk11 and jre11 fdk images, which are published for arm64 as well
+func (lh *KotlinLangHelper) Platforms() []string {
+	return []string{"linux/amd64", "linux/arm64"}
+}
+
 const (
 	mavenKotlinPomFile = `<?xml version="1.0" encoding="UTF-8"?>
 <project xmlns="http://maven.apache.org/POM/4.0.0"
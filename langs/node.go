@@ -111,11 +111,11 @@ func (h *NodeLangHelper) Entrypoint() (string, error) {
 	return "node func.js", nil
 }
 
-func (h *NodeLangHelper) DockerfileBuildCmds() []string {
+func (h *NodeLangHelper) DockerfileBuildCmds(dir string) []string {
 	r := []string{}
 	// skip npm -install if node_modules is local - allows local development
-	if exists("package.json") && !exists("node_modules") {
-		if exists("package-lock.json") {
+	if exists(filepath.Join(dir, "package.json")) && !exists(filepath.Join(dir, "node_modules")) {
+		if exists(filepath.Join(dir, "package-lock.json")) {
 			r = append(r, "ADD package-lock.json /function/")
 		}
 
@@ -127,10 +127,30 @@ func (h *NodeLangHelper) DockerfileBuildCmds() []string {
 	return r
 }
 
-func (h *NodeLangHelper) DockerfileCopyCmds() []string {
+// CacheDirs - the npm cache
+func (h *NodeLangHelper) CacheDirs() []string {
+	return []string{"/root/.npm"}
+}
+
+// SecretMounts - an npmrc secret is used as the user .npmrc, eg: for the credentials of a private registry
+func (h *NodeLangHelper) SecretMounts() map[string]string {
+	return map[string]string{"npmrc": "/root/.npmrc"}
+}
+
+// DockerIgnore - npm logs. A local node_modules is kept, it is used instead of running npm install
+func (h *NodeLangHelper) DockerIgnore(dir string) []string {
+	return []string{"npm-debug.log*"}
+}
+
+// Dependencies - the packages in package-lock.json, or package.json without one
+func (h *NodeLangHelper) Dependencies(dir string) ([]Dependency, error) {
+	return npmDependencies(dir)
+}
+
+func (h *NodeLangHelper) DockerfileCopyCmds(dir string) []string {
 	// excessive but content could be anything really
 	r := []string{"ADD . /function/"}
-	if exists("package.json") && !exists("node_modules") {
+	if exists(filepath.Join(dir, "package.json")) && !exists(filepath.Join(dir, "node_modules")) {
 		r = append(r, "COPY --from=build-stage /function/node_modules/ /function/node_modules/")
 	}
 	r = append(r, "RUN chmod -R o+r /function")
@@ -91,22 +91,36 @@ func (h *PythonLangHelper) Entrypoint() (string, error) {
 	return "/python/bin/fdk /function/func.py handler", nil
 }
 
-func (h *PythonLangHelper) DockerfileBuildCmds() []string {
+func (h *PythonLangHelper) DockerfileBuildCmds(dir string) []string {
+	return pythonBuildCmds(dir, false)
+}
+
+// DockerfileCachedBuildCmds leaves the pip cache in the cache mount of CacheDirs
+func (h *PythonLangHelper) DockerfileCachedBuildCmds(dir string) []string {
+	return pythonBuildCmds(dir, true)
+}
+
+func pythonBuildCmds(dir string, cached bool) []string {
 	var r []string
-	if exists("requirements.txt") {
-		pip_cmd := `RUN pip3 install --target /python/  --no-cache --no-cache-dir`
-		if exists(".pip_cache") {
+	if exists(filepath.Join(dir, "requirements.txt")) {
+		pip_cmd := `RUN pip3 install --target /python/`
+		cleanup := "/tmp* requirements.txt func.yaml Dockerfile .venv"
+		if !cached {
+			pip_cmd += "  --no-cache --no-cache-dir"
+			cleanup = "~/.cache/pip " + cleanup
+		}
+		if exists(filepath.Join(dir, ".pip_cache")) {
 			r = append(r, "ADD .pip_cache /function/.pip_cache")
 			pip_cmd += " --no-index --find-links /function/.pip_cache"
 		}
 		r = append(r, "ADD requirements.txt /function/")
 		r = append(r, fmt.Sprintf(`
 			%v -r requirements.txt &&\
-			    rm -fr ~/.cache/pip /tmp* requirements.txt func.yaml Dockerfile .venv &&\
-			    chmod -R o+r /python`, pip_cmd))
+			    rm -fr %v &&\
+			    chmod -R o+r /python`, pip_cmd, cleanup))
 	}
 	r = append(r, "ADD . /function/")
-	if exists("setup.py") {
+	if exists(filepath.Join(dir, "setup.py")) {
 		r = append(r, "python setup.py install")
 	}
 	r = append(r, "RUN rm -fr /function/.pip_cache")
@@ -114,6 +128,21 @@ func (h *PythonLangHelper) DockerfileBuildCmds() []string {
 	return r
 }
 
+// CacheDirs - the pip cache
+func (h *PythonLangHelper) CacheDirs() []string {
+	return []string{"/root/.cache/pip"}
+}
+
+// DockerIgnore - local virtual environments and bytecode, packages are installed from requirements.txt
+func (h *PythonLangHelper) DockerIgnore(dir string) []string {
+	return []string{".venv", "venv", "**/__pycache__", "**/*.pyc"}
+}
+
+// Dependencies - the packages in requirements.txt
+func (h *PythonLangHelper) Dependencies(dir string) ([]Dependency, error) {
+	return pipDependencies(dir)
+}
+
 func (h *PythonLangHelper) IsMultiStage() bool {
 	return true
 }
@@ -144,7 +173,7 @@ def handler(ctx, data: io.BytesIO = None):
 	reqsPythonSrcBoilerplate = `fdk%s`
 )
 
-func (h *PythonLangHelper) DockerfileCopyCmds() []string {
+func (h *PythonLangHelper) DockerfileCopyCmds(dir string) []string {
 	return []string{
 		"COPY --from=build-stage /python /python",
 		"COPY --from=build-stage /function /function",
@@ -56,9 +56,9 @@ func (h *RubyLangHelper) RunFromImage() (string, error) {
 	return fmt.Sprintf("fnproject/ruby:%s", h.Version), nil
 }
 
-func (h *RubyLangHelper) DockerfileBuildCmds() []string {
+func (h *RubyLangHelper) DockerfileBuildCmds(dir string) []string {
 	r := []string{}
-	if exists("Gemfile") {
+	if exists(filepath.Join(dir, "Gemfile")) {
 		r = append(r,
 			"ADD Gemfile* /function/",
 			"RUN bundle install",
@@ -67,7 +67,17 @@ func (h *RubyLangHelper) DockerfileBuildCmds() []string {
 	return r
 }
 
-func (h *RubyLangHelper) DockerfileCopyCmds() []string {
+// DockerIgnore - locally installed gems, bundle install installs them in the build stage
+func (h *RubyLangHelper) DockerIgnore(dir string) []string {
+	return []string{".bundle", "vendor/bundle"}
+}
+
+// Dependencies - the gems in Gemfile.lock
+func (h *RubyLangHelper) Dependencies(dir string) ([]Dependency, error) {
+	return gemDependencies(dir)
+}
+
+func (h *RubyLangHelper) DockerfileCopyCmds(dir string) []string {
 	return []string{
 		"COPY --from=build-stage /usr/lib/ruby/gems/ /usr/lib/ruby/gems/", // skip this if no Gemfile?  Does it matter?
 		"COPY . /function/",
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"text/tabwriter"
 
@@ -155,6 +156,11 @@ func (a *appsCmd) create(c *cli.Context) error {
 
 // CreateApp creates a new app using the given client
 func CreateApp(a *fnclient.Fn, app *modelsv2.App) (*modelsv2.App, error) {
+	return CreateAppTo(os.Stdout, a, app)
+}
+
+// CreateAppTo creates a new app using the given client, printing that it was created to out
+func CreateAppTo(out io.Writer, a *fnclient.Fn, app *modelsv2.App) (*modelsv2.App, error) {
 	resp, err := a.Apps.CreateApp(&apiapps.CreateAppParams{
 		Context: context.Background(),
 		Body:    app,
@@ -170,7 +176,7 @@ func CreateApp(a *fnclient.Fn, app *modelsv2.App) (*modelsv2.App, error) {
 		return nil, err
 	}
 
-	fmt.Println("Successfully created app: ", resp.Payload.Name)
+	fmt.Fprintln(out, "Successfully created app: ", resp.Payload.Name)
 	return resp.Payload, nil
 }
 
@@ -167,6 +167,10 @@ func Inspect() cli.Command {
 				Name:  "endpoint",
 				Usage: "Output the function invoke endpoint if set",
 			},
+			cli.BoolFlag{
+				Name:  "labels",
+				Usage: "Output the labels of the function image, or the label given as property, pulling the image if needed",
+			},
 		},
 		ArgsUsage: "<app-name> <function-name> [property[.key]]",
 		Action:    f.inspect,
@@ -231,6 +235,53 @@ func Update() cli.Command {
 	}
 }
 
+// Rollback function command
+func Rollback() cli.Command {
+	f := fnsCmd{}
+	return cli.Command{
+		Name:        "function",
+		ShortName:   "func",
+		Aliases:     []string{"f", "fn"},
+		Category:    "MANAGEMENT COMMAND",
+		Usage:       "Roll a function back to an earlier revision",
+		Description: "This command restores the image, memory, timeouts and config a function had before an earlier deploy. Revisions are recorded by fn deploy under the root of the app tree, the directory with app.yaml, so run this command from anywhere in the same tree.",
+		Before: func(c *cli.Context) error {
+			var err error
+			f.provider, err = client.CurrentProvider()
+			if err != nil {
+				return err
+			}
+			f.client = f.provider.APIClientv2()
+			return nil
+		},
+		ArgsUsage: "<app-name> <function-name>",
+		Action:    f.rollback,
+		Flags: []cli.Flag{
+			cli.IntFlag{
+				Name:  "to",
+				Usage: "Revision to roll back to, 1 is the revision before the current one",
+				Value: 1,
+			},
+			cli.BoolFlag{
+				Name:  "list",
+				Usage: "List the recorded revisions of the function instead of rolling back",
+			},
+			cli.StringFlag{
+				Name:  "working-dir,w",
+				Usage: "Specify a directory in the app tree the function was deployed from, must be the full path.",
+			},
+		},
+		BashComplete: func(c *cli.Context) {
+			switch len(c.Args()) {
+			case 0:
+				app.BashCompleteApps(c)
+			case 1:
+				BashCompleteFns(c)
+			}
+		},
+	}
+}
+
 // GetConfig for function command
 func GetConfig() cli.Command {
 	f := fnsCmd{}
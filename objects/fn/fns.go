@@ -21,10 +21,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	client "github.com/fnproject/cli/client"
 	"github.com/fnproject/cli/common"
@@ -286,6 +288,11 @@ func (f *fnsCmd) create(c *cli.Context) error {
 
 // CreateFn request
 func CreateFn(r *fnclient.Fn, appID string, fn *models.Fn) (*models.Fn, error) {
+	return CreateFnTo(os.Stdout, r, appID, fn)
+}
+
+// CreateFnTo creates fn in the app with appID, printing that it was created to out
+func CreateFnTo(out io.Writer, r *fnclient.Fn, appID string, fn *models.Fn) (*models.Fn, error) {
 	fn.AppID = appID
 	err := common.ValidateTagImageName(fn.Image)
 	if err != nil {
@@ -307,7 +314,7 @@ func CreateFn(r *fnclient.Fn, appID string, fn *models.Fn) (*models.Fn, error) {
 		return nil, err
 	}
 
-	fmt.Println("Successfully created function:", resp.Payload.Name, "with", resp.Payload.Image)
+	fmt.Fprintln(out, "Successfully created function:", resp.Payload.Name, "with", resp.Payload.Image)
 	return resp.Payload, nil
 }
 
@@ -529,12 +536,32 @@ func (f *fnsCmd) inspect(c *cli.Context) error {
 	enc := json.NewEncoder(os.Stdout)
 	enc.SetIndent("", "\t")
 
+	if c.Bool("labels") {
+		labels, err := common.ImageLabels(fn.Image)
+		if err != nil {
+			return err
+		}
+		if prop == "" {
+			enc.Encode(labels)
+			return nil
+		}
+		label, ok := labels[prop]
+		if !ok {
+			return fmt.Errorf("image %s has no label %s", fn.Image, prop)
+		}
+		fmt.Println(label)
+		return nil
+	}
+
+	inspected := inspectFn{Fn: fn}
+	inspected.ImageTag, inspected.ImageDigest = imageRefs(fn)
+
 	if prop == "" {
-		enc.Encode(fn)
+		enc.Encode(inspected)
 		return nil
 	}
 
-	data, err := json.Marshal(fn)
+	data, err := json.Marshal(inspected)
 	if err != nil {
 		return fmt.Errorf("failed to inspect %s: %s", fnName, err)
 	}
@@ -554,6 +581,22 @@ func (f *fnsCmd) inspect(c *cli.Context) error {
 	return nil
 }
 
+// inspectFn is a function as shown by inspect, with the tag and digest of its image
+type inspectFn struct {
+	*models.Fn
+	ImageTag    string `json:"image_tag,omitempty"`
+	ImageDigest string `json:"image_digest,omitempty"`
+}
+
+// imageRefs returns the tag and digest references of the image of fn, as far as they are known
+func imageRefs(fn *models.Fn) (tag, digest string) {
+	if !strings.Contains(fn.Image, "@") {
+		return fn.Image, ""
+	}
+	tag, _ = fn.Annotations[common.ImageTagAnnotation].(string)
+	return tag, fn.Image
+}
+
 func (f *fnsCmd) delete(c *cli.Context) error {
 	appName := c.Args().Get(0)
 	fnName := c.Args().Get(1)
@@ -603,3 +646,61 @@ func (f *fnsCmd) delete(c *cli.Context) error {
 	fmt.Println("Function", fnName, "deleted")
 	return nil
 }
+
+func (f *fnsCmd) rollback(c *cli.Context) error {
+	appName := c.Args().Get(0)
+	fnName := WithoutSlash(c.Args().Get(1))
+
+	state, err := common.LoadDeployState(common.GetDir(c))
+	if err != nil {
+		return err
+	}
+	revisions := state.Revisions(appName, fnName)
+
+	if c.Bool("list") {
+		return printRevisions(revisions)
+	}
+
+	to := c.Int("to")
+	if len(revisions) == 0 {
+		return fmt.Errorf("no earlier revisions of function %s recorded in this app tree, deploy it from the tree first", fnName)
+	}
+	if to < 1 || to > len(revisions) {
+		return fmt.Errorf("--to must be between 1 and %d, the number of recorded revisions of function %s", len(revisions), fnName)
+	}
+	rev := revisions[to-1]
+
+	app, err := app.GetAppByName(f.client, appName)
+	if err != nil {
+		return err
+	}
+	fn, err := GetFnByName(f.client, app.ID, fnName)
+	if err != nil {
+		return err
+	}
+
+	err = PutFn(f.client, fn.ID, rev.RollbackFn(fn))
+	if err != nil {
+		return err
+	}
+
+	// the replaced revision is kept so that the rollback can itself be rolled back
+	if err := state.AddRevision(appName, fnName, common.NewFuncRevision(fn)); err != nil {
+		return err
+	}
+	if err := state.Forget(appName, fnName); err != nil {
+		return err
+	}
+
+	fmt.Println(appName, fnName, "rolled back to", rev.Image)
+	return nil
+}
+
+func printRevisions(revisions []*common.FuncRevision) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprint(w, "N", "\t", "IMAGE", "\t", "REPLACED", "\n")
+	for i, rev := range revisions {
+		fmt.Fprint(w, i+1, "\t", rev.Image, "\t", rev.ReplacedAt.Format(time.RFC3339), "\n")
+	}
+	return w.Flush()
+}
@@ -19,7 +19,6 @@ package server
 import (
 	"log"
 	"os"
-	"os/exec"
 	"os/signal"
 	"syscall"
 
@@ -31,10 +30,14 @@ func update(c *cli.Context) error {
 	args := []string{"pull",
 		common.FunctionsDockerImage,
 	}
-	cmd := exec.Command("docker", args...)
+	engine, err := common.Engine()
+	if err != nil {
+		return err
+	}
+	cmd := engine.Command(args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	err := cmd.Start()
+	err = cmd.Start()
 	if err != nil {
 		log.Fatalln("Starting command failed:", err)
 	}
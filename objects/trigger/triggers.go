@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"text/tabwriter"
@@ -109,6 +110,11 @@ func validateTriggerSource(ts string) string {
 
 // CreateTrigger request
 func CreateTrigger(client *fnclient.Fn, trigger *models.Trigger) error {
+	return CreateTriggerTo(os.Stdout, client, trigger)
+}
+
+// CreateTriggerTo creates trigger, printing it and its endpoint to out
+func CreateTriggerTo(out io.Writer, client *fnclient.Fn, trigger *models.Trigger) error {
 	resp, err := client.Triggers.CreateTrigger(&apiTriggers.CreateTriggerParams{
 		Context: context.Background(),
 		Body:    trigger,
@@ -117,7 +123,7 @@ func CreateTrigger(client *fnclient.Fn, trigger *models.Trigger) error {
 	if err != nil {
 		switch e := err.(type) {
 		case *apiTriggers.CreateTriggerBadRequest:
-			fmt.Println(e)
+			fmt.Fprintln(out, e)
 			return fmt.Errorf("%s", e.Payload.Message)
 		case *apiTriggers.CreateTriggerConflict:
 			return fmt.Errorf("%s", e.Payload.Message)
@@ -126,9 +132,9 @@ func CreateTrigger(client *fnclient.Fn, trigger *models.Trigger) error {
 		}
 	}
 
-	fmt.Println("Successfully created trigger:", resp.Payload.Name)
+	fmt.Fprintln(out, "Successfully created trigger:", resp.Payload.Name)
 	endpoint := resp.Payload.Annotations["fnproject.io/trigger/httpEndpoint"]
-	fmt.Println("Trigger Endpoint:", endpoint)
+	fmt.Fprintln(out, "Trigger Endpoint:", endpoint)
 
 	return nil
 }